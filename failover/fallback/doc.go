@@ -0,0 +1,12 @@
+/*
+Package fallback 实现了降级调用链。依次尝试主服务、备用服务，全部失败时返回静态默认值
+
+	res, err := fallback.New("primary", func(ctx context.Context) (interface{}, error) {
+		return primary.Get(ctx, key)
+	}).Or("secondary", func(ctx context.Context) (interface{}, error) {
+		return secondary.Get(ctx, key)
+	}).Default("").Success(func(branch string, result interface{}) {
+		fmt.Printf("answered by %s\n", branch)
+	}).Run(ctx)
+*/
+package fallback
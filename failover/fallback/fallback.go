@@ -0,0 +1,158 @@
+package fallback
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// DefaultBranch 使用静态默认值时，结果中的分支名称
+const DefaultBranch = "default"
+
+// Provider 降级链中的服务提供函数
+type Provider func(ctx context.Context) (interface{}, error)
+
+type branch struct {
+	name     string
+	provider Provider
+}
+
+// Result 降级链执行结果
+type Result struct {
+	// Branch 最终返回结果的分支名称，使用默认值时为 DefaultBranch，全部失败时为空
+	Branch string
+	// Index 最终返回结果的分支序号，使用默认值时为分支数量，全部失败时为 -1
+	Index int
+	// Value 返回值
+	Value interface{}
+	// Errors 前面各个失败分支的错误，按照执行顺序排列
+	Errors []error
+	// Duration 整个降级链的执行耗时
+	Duration time.Duration
+}
+
+// Chain 降级调用链
+type Chain struct {
+	branches     []branch
+	hasDefault   bool
+	defaultValue interface{}
+	fallbackFunc func(branch string, err error)
+	successFunc  func(branch string, result interface{})
+	failedFunc   func(err error)
+	finishFunc   func(branch string, err error) bool
+}
+
+// New 创建一个降级调用链，name 为首个（主）服务的名称
+func New(name string, provider Provider) *Chain {
+	return &Chain{
+		branches:     []branch{{name: name, provider: provider}},
+		fallbackFunc: func(branch string, err error) {},
+		successFunc:  func(branch string, result interface{}) {},
+		failedFunc:   func(err error) {},
+		finishFunc: func(branch string, err error) bool {
+			return false
+		},
+	}
+}
+
+// Or 添加一个备用服务，前面的服务全部失败后才会调用
+func (c *Chain) Or(name string, provider Provider) *Chain {
+	c.branches = append(c.branches, branch{name: name, provider: provider})
+
+	return c
+}
+
+// Default 设置所有服务都失败后返回的静态默认值
+func (c *Chain) Default(value interface{}) *Chain {
+	c.hasDefault = true
+	c.defaultValue = value
+
+	return c
+}
+
+// Fallback 注册分支失败，降级到下一个分支时执行的函数
+func (c *Chain) Fallback(fallbackFunc func(branch string, err error)) *Chain {
+	c.fallbackFunc = fallbackFunc
+
+	return c
+}
+
+// Success 注册执行成功后置函数（包括使用默认值的情况），branch 为返回结果的分支名称
+func (c *Chain) Success(successFunc func(branch string, result interface{})) *Chain {
+	c.successFunc = successFunc
+
+	return c
+}
+
+// Failed 注册执行失败后置函数（所有分支均失败并且没有默认值时才调用）
+func (c *Chain) Failed(failedFunc func(err error)) *Chain {
+	c.failedFunc = failedFunc
+
+	return c
+}
+
+// Finished 注册无论成功失败，最终执行完毕后执行的后置函数，返回 true 时不再调用 Success 和 Failed
+func (c *Chain) Finished(finishFunc func(branch string, err error) bool) *Chain {
+	c.finishFunc = finishFunc
+
+	return c
+}
+
+// Run 依次执行降级链中的各个分支，返回第一个成功的结果，ctx 取消后不再尝试后续分支
+func (c *Chain) Run(ctx context.Context) (Result, error) {
+	startTime := time.Now()
+	result := Result{Index: -1, Errors: make([]error, 0)}
+
+	var lastErr error
+	for i, b := range c.branches {
+		if ctx.Err() != nil {
+			lastErr = ctx.Err()
+			break
+		}
+
+		value, err := callWithRecover(ctx, b.provider)
+		if err == nil {
+			result.Branch, result.Index, result.Value = b.name, i, value
+			result.Duration = time.Since(startTime)
+
+			if !c.finishFunc(b.name, nil) {
+				c.successFunc(b.name, value)
+			}
+
+			return result, nil
+		}
+
+		lastErr = fmt.Errorf("%s: %s", b.name, err)
+		result.Errors = append(result.Errors, lastErr)
+
+		if i < len(c.branches)-1 || c.hasDefault {
+			c.fallbackFunc(b.name, err)
+		}
+	}
+
+	result.Duration = time.Since(startTime)
+
+	if c.hasDefault {
+		result.Branch, result.Index, result.Value = DefaultBranch, len(c.branches), c.defaultValue
+		if !c.finishFunc(DefaultBranch, nil) {
+			c.successFunc(DefaultBranch, c.defaultValue)
+		}
+
+		return result, nil
+	}
+
+	if !c.finishFunc("", lastErr) {
+		c.failedFunc(lastErr)
+	}
+
+	return result, lastErr
+}
+
+func callWithRecover(ctx context.Context, provider Provider) (res interface{}, err error) {
+	defer func() {
+		if err2 := recover(); err2 != nil {
+			err = fmt.Errorf("%v", err2)
+		}
+	}()
+	return provider(ctx)
+}
@@ -0,0 +1,80 @@
+package fallback
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestFallbackChain(t *testing.T) {
+	fallbacks := make([]string, 0)
+	answered := ""
+
+	res, err := New("primary", func(ctx context.Context) (interface{}, error) {
+		return nil, errors.New("primary down")
+	}).Or("secondary", func(ctx context.Context) (interface{}, error) {
+		panic("sorry")
+	}).Or("tertiary", func(ctx context.Context) (interface{}, error) {
+		return 42, nil
+	}).Default(0).Fallback(func(branch string, err error) {
+		fallbacks = append(fallbacks, branch)
+	}).Success(func(branch string, result interface{}) {
+		answered = branch
+	}).Run(context.Background())
+
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if res.Branch != "tertiary" || res.Index != 2 || res.Value != 42 || answered != "tertiary" {
+		t.Errorf("test failed, got %+v", res)
+	}
+
+	if len(res.Errors) != 2 || len(fallbacks) != 2 {
+		t.Errorf("test failed, expect 2 fallbacks, got %v", fallbacks)
+	}
+}
+
+func TestFallbackDefault(t *testing.T) {
+	failing := func(ctx context.Context) (interface{}, error) {
+		return nil, errors.New("down")
+	}
+
+	res, err := New("primary", failing).Or("secondary", failing).Default("static").Run(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if res.Branch != DefaultBranch || res.Value != "static" {
+		t.Errorf("test failed, got %+v", res)
+	}
+
+	failed := false
+	res, err = New("primary", failing).Failed(func(err error) {
+		failed = true
+	}).Run(context.Background())
+	if err == nil || !failed || res.Index != -1 {
+		t.Error("test failed, expect error without default value")
+	}
+}
+
+func TestFallbackCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	called := false
+	_, err := New("primary", func(ctx context.Context) (interface{}, error) {
+		cancel()
+		return nil, ctx.Err()
+	}).Or("secondary", func(ctx context.Context) (interface{}, error) {
+		called = true
+		return "ok", nil
+	}).Run(ctx)
+
+	if err != context.Canceled {
+		t.Errorf("test failed, expect %v, got %v", context.Canceled, err)
+	}
+
+	if called {
+		t.Error("test failed, secondary should not be called after cancel")
+	}
+}
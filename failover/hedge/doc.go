@@ -0,0 +1,12 @@
+/*
+Package hedge 实现了对冲请求（Hedged Requests）。首个请求在指定延迟（或者历史延迟的百分位数）内没有返回时，
+发起下一个请求，取最先成功的结果，并取消其余仍在执行中的请求
+
+	tracker := hedge.NewLatencyTracker(100)
+	res, err := hedge.Hedge(func(ctx context.Context, branch int) (interface{}, error) {
+		return query(ctx, backends[branch])
+	}, 50*time.Millisecond, 3).Percentile(tracker, 0.95).Success(func(branch int, result interface{}) {
+		fmt.Printf("branch %d answered\n", branch)
+	}).Run(ctx)
+*/
+package hedge
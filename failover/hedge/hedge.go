@@ -0,0 +1,175 @@
+package hedge
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Result 对冲请求执行结果
+type Result struct {
+	// Branch 最终返回结果的分支编号，从 0 开始，全部失败时为 -1
+	Branch int
+	// Value 返回结果的分支的返回值
+	Value interface{}
+	// Launched 实际发起的请求数
+	Launched int
+	// Duration 从发起首个请求到获得结果的耗时
+	Duration time.Duration
+}
+
+// Hedger 对冲请求执行器
+type Hedger struct {
+	f           func(ctx context.Context, branch int) (interface{}, error)
+	delay       time.Duration
+	maxBranches int
+	tracker     *LatencyTracker
+	percentile  float64
+	successFunc func(branch int, result interface{})
+	failedFunc  func(err error)
+	finishFunc  func(branch int, err error) bool
+}
+
+type branchResult struct {
+	branch   int
+	value    interface{}
+	err      error
+	duration time.Duration
+}
+
+// Hedge 创建对冲请求执行器，前一个请求发出 delay 时间后仍未返回（或者已经失败），则发起下一个请求，最多发起 max 个请求
+func Hedge(f func(ctx context.Context, branch int) (interface{}, error), delay time.Duration, max int) *Hedger {
+	if max < 1 {
+		max = 1
+	}
+
+	return &Hedger{
+		f:           f,
+		delay:       delay,
+		maxBranches: max,
+		successFunc: func(branch int, result interface{}) {},
+		failedFunc:  func(err error) {},
+		finishFunc: func(branch int, err error) bool {
+			return false
+		},
+	}
+}
+
+// Percentile 使用历史延迟的百分位数（0-1之间）作为发起下一个请求的阈值，样本不足时使用 Hedge 中指定的 delay
+// 成功请求的耗时会自动记录到 tracker 中
+func (h *Hedger) Percentile(tracker *LatencyTracker, p float64) *Hedger {
+	h.tracker = tracker
+	h.percentile = p
+
+	return h
+}
+
+// Success 注册执行成功后置函数，branch 为返回结果的分支编号
+func (h *Hedger) Success(successFunc func(branch int, result interface{})) *Hedger {
+	h.successFunc = successFunc
+
+	return h
+}
+
+// Failed 注册执行失败后置函数（所有分支均失败后才调用）
+func (h *Hedger) Failed(failedFunc func(err error)) *Hedger {
+	h.failedFunc = failedFunc
+
+	return h
+}
+
+// Finished 注册无论成功失败，最终执行完毕后执行的后置函数，返回 true 时不再调用 Success 和 Failed
+func (h *Hedger) Finished(finishFunc func(branch int, err error) bool) *Hedger {
+	h.finishFunc = finishFunc
+
+	return h
+}
+
+// hedgeDelay 发起下一个请求前的等待时间
+func (h *Hedger) hedgeDelay() time.Duration {
+	if h.tracker != nil {
+		if d := h.tracker.Percentile(h.percentile); d > 0 {
+			return d
+		}
+	}
+
+	return h.delay
+}
+
+// Run 执行请求，返回最先成功的分支结果，其余仍在执行中的请求会通过 ctx 取消
+func (h *Hedger) Run(ctx context.Context) (Result, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	startTime := time.Now()
+	results := make(chan branchResult, h.maxBranches)
+
+	launched := 0
+	launch := func() <-chan time.Time {
+		branch := launched
+		launched++
+
+		go func() {
+			branchStart := time.Now()
+			value, err := callWithRecover(ctx, branch, h.f)
+			results <- branchResult{branch: branch, value: value, err: err, duration: time.Since(branchStart)}
+		}()
+
+		if launched < h.maxBranches {
+			return time.After(h.hedgeDelay())
+		}
+
+		return nil
+	}
+
+	next := launch()
+
+	var lastErr error
+	finished := 0
+	for {
+		select {
+		case res := <-results:
+			finished++
+			if res.err == nil {
+				if h.tracker != nil {
+					h.tracker.Observe(res.duration)
+				}
+
+				result := Result{Branch: res.branch, Value: res.value, Launched: launched, Duration: time.Since(startTime)}
+				if !h.finishFunc(res.branch, nil) {
+					h.successFunc(res.branch, res.value)
+				}
+
+				return result, nil
+			}
+
+			lastErr = res.err
+			if launched < h.maxBranches {
+				next = launch()
+			} else if finished == launched {
+				return h.failed(lastErr, launched, startTime)
+			}
+		case <-next:
+			next = launch()
+		case <-ctx.Done():
+			return h.failed(ctx.Err(), launched, startTime)
+		}
+	}
+}
+
+func (h *Hedger) failed(err error, launched int, startTime time.Time) (Result, error) {
+	if !h.finishFunc(-1, err) {
+		h.failedFunc(err)
+	}
+
+	return Result{Branch: -1, Launched: launched, Duration: time.Since(startTime)}, err
+}
+
+func callWithRecover(ctx context.Context, branch int, f func(ctx context.Context, branch int) (interface{}, error)) (res interface{}, err error) {
+	defer func() {
+		if err2 := recover(); err2 != nil {
+			err = fmt.Errorf("%v", err2)
+		}
+	}()
+	return f(ctx, branch)
+}
@@ -0,0 +1,95 @@
+package hedge
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestHedgeSlowPrimary(t *testing.T) {
+	cancelled := make(chan int, 3)
+
+	res, err := Hedge(func(ctx context.Context, branch int) (interface{}, error) {
+		if branch == 0 {
+			select {
+			case <-time.After(time.Second):
+				return "slow", nil
+			case <-ctx.Done():
+				cancelled <- branch
+				return nil, ctx.Err()
+			}
+		}
+
+		return "fast", nil
+	}, 20*time.Millisecond, 3).Run(context.Background())
+
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if res.Branch != 1 || res.Value != "fast" {
+		t.Errorf("test failed, expect branch 1 with fast, got %d with %v", res.Branch, res.Value)
+	}
+
+	if res.Launched != 2 {
+		t.Errorf("test failed, expect %d launched, got %d", 2, res.Launched)
+	}
+
+	select {
+	case branch := <-cancelled:
+		if branch != 0 {
+			t.Errorf("test failed, expect branch 0 cancelled, got %d", branch)
+		}
+	case <-time.After(500 * time.Millisecond):
+		t.Error("slow branch should be cancelled")
+	}
+}
+
+func TestHedgeFailedBranches(t *testing.T) {
+	var failedErr error
+	res, err := Hedge(func(ctx context.Context, branch int) (interface{}, error) {
+		if branch == 1 {
+			panic("sorry")
+		}
+		return nil, errors.New("test error")
+	}, time.Second, 2).Failed(func(err error) {
+		failedErr = err
+	}).Run(context.Background())
+
+	if err == nil || failedErr == nil {
+		t.Fatal("test failed, expect error")
+	}
+
+	if res.Branch != -1 || res.Launched != 2 {
+		t.Errorf("test failed, got branch=%d, launched=%d", res.Branch, res.Launched)
+	}
+
+	// the failed branch triggers the next one immediately instead of waiting for delay
+	if res.Duration > 500*time.Millisecond {
+		t.Errorf("test failed, hedged request should be launched immediately after failure")
+	}
+}
+
+func TestLatencyTracker(t *testing.T) {
+	tracker := NewLatencyTracker(10)
+	if tracker.Percentile(0.9) != 0 {
+		t.Error("test failed, expect 0 for empty tracker")
+	}
+
+	for i := 1; i <= 20; i++ {
+		tracker.Observe(time.Duration(i) * time.Millisecond)
+	}
+
+	if tracker.Count() != 10 {
+		t.Errorf("test failed, expect %d, got %d", 10, tracker.Count())
+	}
+
+	if p := tracker.Percentile(0.5); p != 15*time.Millisecond {
+		t.Errorf("test failed, expect %s, got %s", 15*time.Millisecond, p)
+	}
+
+	if p := tracker.Percentile(1); p != 20*time.Millisecond {
+		t.Errorf("test failed, expect %s, got %s", 20*time.Millisecond, p)
+	}
+}
@@ -0,0 +1,79 @@
+package hedge
+
+import (
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+// LatencyTracker 记录最近若干次请求的耗时，用于计算百分位数
+type LatencyTracker struct {
+	lock    sync.Mutex
+	samples []time.Duration
+	next    int
+	full    bool
+}
+
+// NewLatencyTracker 创建一个最多保留 size 个样本的延迟统计器
+func NewLatencyTracker(size int) *LatencyTracker {
+	if size < 1 {
+		size = 1
+	}
+
+	return &LatencyTracker{
+		samples: make([]time.Duration, size),
+	}
+}
+
+// Observe 记录一次请求耗时
+func (t *LatencyTracker) Observe(d time.Duration) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	t.samples[t.next] = d
+	t.next = (t.next + 1) % len(t.samples)
+	if t.next == 0 {
+		t.full = true
+	}
+}
+
+// Count 返回当前样本数量
+func (t *LatencyTracker) Count() int {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if t.full {
+		return len(t.samples)
+	}
+
+	return t.next
+}
+
+// Percentile 返回样本的百分位数，p 取值 0-1，没有样本时返回 0
+func (t *LatencyTracker) Percentile(p float64) time.Duration {
+	t.lock.Lock()
+	count := t.next
+	if t.full {
+		count = len(t.samples)
+	}
+	samples := make([]time.Duration, count)
+	copy(samples, t.samples[:count])
+	t.lock.Unlock()
+
+	if count == 0 {
+		return 0
+	}
+
+	sort.Slice(samples, func(i, j int) bool { return samples[i] < samples[j] })
+
+	idx := int(math.Ceil(p*float64(count))) - 1
+	if idx < 0 {
+		idx = 0
+	}
+	if idx >= count {
+		idx = count - 1
+	}
+
+	return samples[idx]
+}
@@ -0,0 +1,33 @@
+package retry
+
+import "context"
+
+// Do 执行 f 并返回其结果，失败时根据 opts 中的配置自动重试，默认最多重试 DefaultMaxRetries 次
+//
+//	user, err := retry.Do(ctx, func(ctx context.Context, attempt int) (*User, error) {
+//		return repo.Find(ctx, id)
+//	}, retry.WithMaxRetries(5), retry.WithBackoff(retry.ExponentialBackoff(100*time.Millisecond, 5*time.Second)))
+func Do[T any](ctx context.Context, f func(ctx context.Context, attempt int) (T, error), opts ...Option) (T, error) {
+	conf := newConfig(DefaultMaxRetries)
+	for _, opt := range opts {
+		opt(&conf)
+	}
+
+	var value T
+	e := newEngine(conf)
+	e.run(ctx, func(ctx context.Context, attempt int) error {
+		res, err := f(ctx, attempt)
+		if err == nil {
+			value = res
+		}
+
+		return err
+	})
+
+	if e.err != nil {
+		var empty T
+		return empty, e.err
+	}
+
+	return value, nil
+}
@@ -0,0 +1,95 @@
+package retry
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// engine 重试执行引擎，Retryer 和 Do 共用
+type engine struct {
+	conf       config
+	retryTimes int
+	err        error
+	startTime  time.Time
+	attempts   []Attempt
+}
+
+func newEngine(conf config) *engine {
+	return &engine{
+		conf:     conf,
+		attempts: make([]Attempt, 0),
+	}
+}
+
+// attempt 执行一次，返回是否执行成功
+func (e *engine) attempt(ctx context.Context, f func(ctx context.Context, attempt int) error) bool {
+	if e.startTime.IsZero() {
+		e.startTime = time.Now()
+	}
+
+	at := Attempt{Index: e.retryTimes, Start: time.Now()}
+	e.retryTimes++
+
+	e.err = callWithRecover(ctx, at.Index, f)
+	at.Err = e.err
+	at.Duration = time.Since(at.Start)
+
+	e.attempts = append(e.attempts, at)
+
+	return e.err == nil
+}
+
+// retry 上次执行失败后，持续重试，直到成功、不满足重试条件、ctx 取消或者达到最大重试次数
+func (e *engine) retry(ctx context.Context, f func(ctx context.Context, attempt int) error) {
+	for e.err != nil && e.retryTimes < e.conf.maxRetries+1 && e.conf.retryIf(e.err) {
+		// ctx 已经取消时不再重试，也不触发 onRetry
+		if err := ctx.Err(); err != nil {
+			e.err = err
+			return
+		}
+
+		delay := e.conf.backoff(e.retryTimes)
+
+		last := &e.attempts[len(e.attempts)-1]
+		last.Delay = delay
+		e.conf.onRetry(last.Index, last.Err, delay)
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			e.err = ctx.Err()
+			return
+		}
+
+		e.attempt(ctx, f)
+	}
+}
+
+// run 执行函数，失败时自动重试
+func (e *engine) run(ctx context.Context, f func(ctx context.Context, attempt int) error) {
+	if !e.attempt(ctx, f) {
+		e.retry(ctx, f)
+	}
+}
+
+// result 返回执行结果
+func (e *engine) result() Result {
+	return Result{
+		Times:    e.retryTimes,
+		Err:      e.err,
+		Attempts: e.attempts,
+		Duration: time.Since(e.startTime),
+	}
+}
+
+func callWithRecover(ctx context.Context, attempt int, f func(ctx context.Context, attempt int) error) (err error) {
+	defer func() {
+		if err2 := recover(); err2 != nil {
+			err = fmt.Errorf("%v", err2)
+		}
+	}()
+	return f(ctx, attempt)
+}
@@ -0,0 +1,96 @@
+package retry
+
+import "time"
+
+// DefaultMaxRetries Do 函数默认的最大重试次数
+const DefaultMaxRetries = 3
+
+// Backoff 计算第 retry 次重试（从 1 开始）之前的等待时间
+type Backoff func(retry int) time.Duration
+
+// LinearBackoff 线性增长的等待时间，第 n 次重试前等待 n*step
+func LinearBackoff(step time.Duration) Backoff {
+	return func(retry int) time.Duration {
+		return time.Duration(retry) * step
+	}
+}
+
+// ConstantBackoff 固定的等待时间
+func ConstantBackoff(delay time.Duration) Backoff {
+	return func(retry int) time.Duration {
+		return delay
+	}
+}
+
+// ExponentialBackoff 指数增长的等待时间，第 n 次重试前等待 base*2^(n-1)，最大不超过 max（max 为 0 时不限制）
+func ExponentialBackoff(base time.Duration, max time.Duration) Backoff {
+	return func(retry int) time.Duration {
+		delay := base
+		for i := 1; i < retry; i++ {
+			delay *= 2
+			if max > 0 && delay >= max {
+				return max
+			}
+		}
+
+		if max > 0 && delay > max {
+			return max
+		}
+
+		return delay
+	}
+}
+
+// config 重试配置
+type config struct {
+	maxRetries int
+	backoff    Backoff
+	retryIf    func(err error) bool
+	onRetry    func(attempt int, err error, nextDelay time.Duration)
+}
+
+func newConfig(maxRetries int) config {
+	return config{
+		maxRetries: maxRetries,
+		backoff:    LinearBackoff(time.Second),
+		retryIf:    func(err error) bool { return true },
+		onRetry:    func(attempt int, err error, nextDelay time.Duration) {},
+	}
+}
+
+// Option 重试配置选项
+type Option func(conf *config)
+
+// WithMaxRetries 设置最大重试次数（不包含首次执行）
+func WithMaxRetries(max int) Option {
+	return func(conf *config) {
+		conf.maxRetries = max
+	}
+}
+
+// WithBackoff 设置重试等待策略，默认为 LinearBackoff(time.Second)
+func WithBackoff(backoff Backoff) Option {
+	return func(conf *config) {
+		if backoff != nil {
+			conf.backoff = backoff
+		}
+	}
+}
+
+// WithRetryIf 设置重试条件，predicate 返回 false 时不再重试，直接返回该错误
+func WithRetryIf(predicate func(err error) bool) Option {
+	return func(conf *config) {
+		if predicate != nil {
+			conf.retryIf = predicate
+		}
+	}
+}
+
+// WithOnRetry 设置每次执行失败，准备重试之前执行的函数
+func WithOnRetry(onRetry func(attempt int, err error, nextDelay time.Duration)) Option {
+	return func(conf *config) {
+		if onRetry != nil {
+			conf.onRetry = onRetry
+		}
+	}
+}
@@ -1,55 +1,82 @@
 package retry
 
 import (
-	"fmt"
+	"context"
 	"time"
 )
 
-// retryLatter 用于重试失败的操作
-func retryLatter(f func(), delaySecond int) {
-	fin := make(chan struct{})
-	time.AfterFunc(time.Duration(delaySecond)*time.Second, func() {
-		defer func() { fin <- struct{}{} }()
-		f()
-	})
+// Attempt 单次执行记录
+type Attempt struct {
+	// Index 执行序号，从 0 开始，与传递给重试函数的 retryTimes 一致
+	Index int
+	// Start 开始执行时间
+	Start time.Time
+	// Duration 本次执行耗时
+	Duration time.Duration
+	// Err 本次执行返回的错误
+	Err error
+	// Delay 本次执行失败后，距离下次重试的等待时间，没有后续重试时为 0
+	Delay time.Duration
+}
 
-	<-fin
+// Result 重试执行结果
+type Result struct {
+	// Times 总执行次数
+	Times int
+	// Err 最后一次执行的错误，成功时为 nil
+	Err error
+	// Attempts 每一次执行的记录
+	Attempts []Attempt
+	// Duration 总耗时（包含重试等待时间）
+	Duration time.Duration
 }
 
 // Retryer 重试器
 type Retryer struct {
-	f             func(retryTimes int) error
-	retryTimes    int
-	maxRetryTimes int
-	err           error
-	successFunc   func(retryTimes int)
-	failedFunc    func(err error)
-	finishFunc    func(retryTimes int, err error) bool
-}
-
-func (r *Retryer) retryFunc() {
-	r.retryTimes++
-	if r.err = callWithRecover(r.retryTimes-1, r.f); r.err != nil {
-		if r.retryTimes < r.maxRetryTimes+1 {
-			retryLatter(r.retryFunc, r.retryTimes)
-		}
-	}
+	f           func(retryTimes int) error
+	conf        config
+	successFunc func(retryTimes int)
+	failedFunc  func(err error)
+	finishFunc  func(retryTimes int, err error) bool
 }
 
 // Retry 自动重试函数
 func Retry(f func(retryTimes int) error, max int) *Retryer {
 	return &Retryer{
-		f:             f,
-		maxRetryTimes: max,
-		retryTimes:    0,
-		successFunc:   func(retryTimes int) {},
-		failedFunc:    func(err error) {},
+		f:           f,
+		conf:        newConfig(max),
+		successFunc: func(retryTimes int) {},
+		failedFunc:  func(err error) {},
 		finishFunc: func(retryTimes int, err error) bool {
 			return false
 		},
 	}
 }
 
+// Options 设置重试选项，与 Do 函数使用相同的选项
+func (r *Retryer) Options(opts ...Option) *Retryer {
+	for _, opt := range opts {
+		opt(&r.conf)
+	}
+
+	return r
+}
+
+// Backoff 设置重试等待策略，默认第 n 次重试前等待 n 秒
+func (r *Retryer) Backoff(backoff Backoff) *Retryer {
+	return r.Options(WithBackoff(backoff))
+}
+
+// RetryIf 设置重试条件，predicate 返回 false 时不再重试
+func (r *Retryer) RetryIf(predicate func(err error) bool) *Retryer {
+	return r.Options(WithRetryIf(predicate))
+}
+
+// OnRetry 注册每次执行失败，准备重试之前执行的函数，attempt 为失败的执行序号，nextDelay 为距离下次重试的等待时间
+func (r *Retryer) OnRetry(retryFunc func(attempt int, err error, nextDelay time.Duration)) *Retryer {
+	return r.Options(WithOnRetry(retryFunc))
+}
+
 // Success 注册执行成功后置函数
 func (r *Retryer) Success(successFunc func(retryTimes int)) *Retryer {
 	r.successFunc = successFunc
@@ -71,47 +98,47 @@ func (r *Retryer) Finished(finishFunc func(retryTimes int, err error) bool) *Ret
 	return r
 }
 
-// Run 同步的方式运行
-func (r *Retryer) Run() (int, error) {
-	r.retryFunc()
-	if !r.finishFunc(r.retryTimes, r.err) {
-		if r.err != nil {
-			r.failedFunc(r.err)
+func (r *Retryer) call(ctx context.Context, attempt int) error {
+	return r.f(attempt)
+}
+
+// finish 执行后置函数，返回执行结果
+func (r *Retryer) finish(e *engine) Result {
+	res := e.result()
+	if !r.finishFunc(res.Times, res.Err) {
+		if res.Err != nil {
+			r.failedFunc(res.Err)
 		} else {
-			r.successFunc(r.retryTimes)
+			r.successFunc(res.Times)
 		}
 	}
 
-	return r.retryTimes, r.err
+	return res
 }
 
-// RunAsync 异步方式运行
-func (r *Retryer) RunAsync() <-chan struct{} {
-	fin := make(chan struct{})
+// Run 同步的方式运行
+func (r *Retryer) Run() (Result, error) {
+	e := newEngine(r.conf)
+	e.run(context.Background(), r.call)
 
-	// 由于异步执行模式下，f函数先同步执行了一次
-	// 让重试次数在同步和异步模式下均表现如一
-	r.retryTimes++
+	res := r.finish(e)
+	return res, res.Err
+}
+
+// RunAsync 异步方式运行，执行完毕后，通过返回的 channel 获取执行结果
+func (r *Retryer) RunAsync() <-chan Result {
+	fin := make(chan Result, 1)
 
 	// 异步执行模式下，确保第一次执行是同步的，失败时才异步去重试
-	if r.err = callWithRecover(r.retryTimes-1, r.f); r.err == nil {
-		go func() { fin <- struct{}{} }()
+	e := newEngine(r.conf)
+	if e.attempt(context.Background(), r.call) {
+		fin <- r.finish(e)
 	} else {
-
 		go func() {
-			_, _ = r.Run()
-			fin <- struct{}{}
+			e.retry(context.Background(), r.call)
+			fin <- r.finish(e)
 		}()
 	}
 
 	return fin
 }
-
-func callWithRecover(retryTime int, f func(retryTime int) error) (err error) {
-	defer func() {
-		if err2 := recover(); err2 != nil {
-			err = fmt.Errorf("%v", err2)
-		}
-	}()
-	return f(retryTime)
-}
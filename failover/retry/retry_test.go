@@ -1,6 +1,7 @@
 package retry
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"testing"
@@ -8,7 +9,7 @@ import (
 )
 
 func TestRetryPanic(t *testing.T) {
-	retryTimes, err := Retry(func(retryTimes int) error {
+	res, err := Retry(func(retryTimes int) error {
 		if retryTimes < 1 {
 			panic("sorry")
 		}
@@ -18,15 +19,15 @@ func TestRetryPanic(t *testing.T) {
 		t.Errorf("still error: %s", err.Error())
 	}
 
-	if retryTimes != 2 {
-		t.Errorf("test failed, expect %d, got %d", 2, retryTimes)
+	if res.Times != 2 {
+		t.Errorf("test failed, expect %d, got %d", 2, res.Times)
 	}
 }
 
 func TestRetryLatter(t *testing.T) {
 	fmt.Println("current time: " + time.Now().String())
 
-	retryTimes, err := Retry(func(rt int) error {
+	res, err := Retry(func(rt int) error {
 		fmt.Printf("%d retry execute time: %s\n", rt, time.Now().String())
 		if rt < 1 {
 			return errors.New("test error")
@@ -38,11 +39,11 @@ func TestRetryLatter(t *testing.T) {
 		t.Errorf("still error: %s", err.Error())
 	}
 
-	if retryTimes != 2 {
-		t.Errorf("test failed, expect %d, got %d", 2, retryTimes)
+	if res.Times != 2 {
+		t.Errorf("test failed, expect %d, got %d", 2, res.Times)
 	}
 
-	fmt.Printf("retry %d times\n", retryTimes)
+	fmt.Printf("retry %d times\n", res.Times)
 
 	succeed := false
 	<-Retry(func(rt int) error {
@@ -65,3 +66,113 @@ func TestRetryLatter(t *testing.T) {
 	}
 
 }
+
+func TestRetryObservability(t *testing.T) {
+	retried := make([]int, 0)
+	delays := make([]time.Duration, 0)
+
+	res := <-Retry(func(rt int) error {
+		return fmt.Errorf("error %d", rt)
+	}, 1).OnRetry(func(attempt int, err error, nextDelay time.Duration) {
+		retried = append(retried, attempt)
+		delays = append(delays, nextDelay)
+	}).RunAsync()
+
+	if res.Err == nil || res.Times != 2 || len(res.Attempts) != 2 {
+		t.Fatalf("test failed, got %+v", res)
+	}
+
+	if len(retried) != 1 || retried[0] != 0 || delays[0] != time.Second {
+		t.Errorf("test failed, got retried=%v, delays=%v", retried, delays)
+	}
+
+	for i, at := range res.Attempts {
+		if at.Index != i || at.Err == nil || at.Start.IsZero() {
+			t.Errorf("test failed, invalid attempt %+v", at)
+		}
+	}
+
+	if res.Attempts[0].Delay != time.Second || res.Attempts[1].Delay != 0 {
+		t.Errorf("test failed, invalid attempt delays")
+	}
+
+	if res.Duration < time.Second {
+		t.Errorf("test failed, duration should include retry delay, got %s", res.Duration)
+	}
+}
+
+func TestDo(t *testing.T) {
+	retried := 0
+	val, err := Do(context.Background(), func(ctx context.Context, attempt int) (string, error) {
+		if attempt < 2 {
+			return "", errors.New("test error")
+		}
+		return fmt.Sprintf("value-%d", attempt), nil
+	}, WithBackoff(ConstantBackoff(10*time.Millisecond)), WithOnRetry(func(attempt int, err error, nextDelay time.Duration) {
+		retried++
+	}))
+
+	if err != nil {
+		t.Fatalf("still error: %s", err.Error())
+	}
+
+	if val != "value-2" || retried != 2 {
+		t.Errorf("test failed, got %s with %d retries", val, retried)
+	}
+
+	fatal := errors.New("fatal error")
+	calls := 0
+	_, err = Do(context.Background(), func(ctx context.Context, attempt int) (int, error) {
+		calls++
+		return 0, fatal
+	}, WithMaxRetries(5), WithBackoff(ConstantBackoff(time.Millisecond)), WithRetryIf(func(err error) bool {
+		return err != fatal
+	}))
+
+	if err != fatal || calls != 1 {
+		t.Errorf("test failed, expect no retry for fatal error, got %d calls", calls)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err = Do(ctx, func(ctx context.Context, attempt int) (int, error) {
+		return 0, errors.New("test error")
+	}, WithBackoff(ConstantBackoff(time.Second)))
+
+	if err != context.DeadlineExceeded {
+		t.Errorf("test failed, expect %v, got %v", context.DeadlineExceeded, err)
+	}
+
+	// 执行过程中 ctx 被取消，不再触发 onRetry
+	ctx, cancel = context.WithCancel(context.Background())
+	retried = 0
+	_, err = Do(ctx, func(ctx context.Context, attempt int) (int, error) {
+		cancel()
+		return 0, errors.New("test error")
+	}, WithOnRetry(func(attempt int, err error, nextDelay time.Duration) {
+		retried++
+	}))
+
+	if err != context.Canceled || retried != 0 {
+		t.Errorf("test failed, expect %v without retry, got %v with %d retries", context.Canceled, err, retried)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	backoff := ExponentialBackoff(100*time.Millisecond, time.Second)
+	expects := []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 400 * time.Millisecond, 800 * time.Millisecond, time.Second, time.Second}
+	for i, expect := range expects {
+		if got := backoff(i + 1); got != expect {
+			t.Errorf("test failed, retry %d expect %s, got %s", i+1, expect, got)
+		}
+	}
+
+	res, _ := Retry(func(rt int) error {
+		return errors.New("test error")
+	}, 2).Backoff(ConstantBackoff(time.Millisecond)).Run()
+
+	if res.Times != 3 || res.Attempts[0].Delay != time.Millisecond {
+		t.Errorf("test failed, got %+v", res)
+	}
+}
@@ -1,24 +1,21 @@
 module github.com/mylxsw/go-toolkit
 
 require (
-	github.com/buger/jsonparser v0.0.0-20181115193947-bf1c66bbce23
 	github.com/dgrijalva/jwt-go v3.2.0+incompatible
 	github.com/fatih/color v1.7.0
-	github.com/gopherjs/gopherjs v0.0.0-20190430165422-3e4dfb77656c // indirect
-	github.com/gorilla/context v1.1.1
-	github.com/gorilla/mux v1.7.3
-	github.com/mattn/go-colorable v0.1.2 // indirect
 	github.com/mylxsw/asteria v0.0.0-20190730075526-1867e6bc4dbe
 	github.com/mylxsw/coll v0.0.0-20190810120926-a7a6f0f4bae8
 	github.com/mylxsw/container v0.0.0-20191208075953-c8ee6e3238cc
+	gopkg.in/ini.v1 v1.44.2
+	gopkg.in/yaml.v2 v2.4.0
+)
+
+require (
+	github.com/gopherjs/gopherjs v0.0.0-20190430165422-3e4dfb77656c // indirect
+	github.com/mattn/go-colorable v0.1.2 // indirect
+	github.com/mattn/go-isatty v0.0.8 // indirect
 	github.com/smartystreets/assertions v1.0.1 // indirect
-	github.com/stretchr/objx v0.2.0 // indirect
-	golang.org/x/crypto v0.0.0-20190701094942-4def268fd1a4 // indirect
-	golang.org/x/net v0.0.0-20190724013045-ca1201d0de80 // indirect
 	golang.org/x/sys v0.0.0-20190804053845-51ab0e2deafa // indirect
-	golang.org/x/text v0.3.2 // indirect
-	golang.org/x/tools v0.0.0-20190802220118-1d1727260058 // indirect
-	gopkg.in/ini.v1 v1.44.2
 )
 
-go 1.13
+go 1.18
@@ -0,0 +1,133 @@
+package period_job
+
+import (
+	"context"
+	"time"
+
+	"github.com/mylxsw/asteria/log"
+	"github.com/mylxsw/go-toolkit/events"
+	"github.com/mylxsw/go-toolkit/failover/retry"
+)
+
+// JobFailedEvent 任务连续失败次数达到阈值时触发的事件，在任务恢复之前只触发一次
+type JobFailedEvent struct {
+	Name                string
+	Error               error
+	ConsecutiveFailures int
+	Time                time.Time
+}
+
+// JobRecoveredEvent 任务触发失败事件后，再次执行成功时触发的事件
+type JobRecoveredEvent struct {
+	Name string
+	// Failures 恢复之前连续失败的次数
+	Failures int
+	Time     time.Time
+}
+
+// WithRetry 任务执行失败（包括 panic）时，在同一次调度内最多重试 maxRetries 次，
+// opts 为 failover/retry 包的重试选项，可以设置重试等待策略、重试条件等，WithTimeout 限制的是包含重试在内的总执行时间
+func WithRetry(maxRetries int, opts ...retry.Option) JobOption {
+	return func(o *jobOptions) {
+		o.retries = maxRetries
+		o.retryOptions = opts
+	}
+}
+
+// WithFailureThreshold 设置触发失败通知的连续失败次数，默认为 1
+func WithFailureThreshold(threshold int) JobOption {
+	return func(opts *jobOptions) {
+		opts.failureThreshold = threshold
+	}
+}
+
+// WithFailureHandler 设置任务连续失败次数达到阈值时执行的函数
+func WithFailureHandler(handler func(evt JobFailedEvent)) JobOption {
+	return func(opts *jobOptions) {
+		opts.failureHandler = handler
+	}
+}
+
+// WithRecoveryHandler 设置任务从失败中恢复时执行的函数
+func WithRecoveryHandler(handler func(evt JobRecoveredEvent)) JobOption {
+	return func(opts *jobOptions) {
+		opts.recoveryHandler = handler
+	}
+}
+
+// SetEventManager 设置事件管理器，任务失败和恢复时发布 JobFailedEvent 和 JobRecoveredEvent 事件
+func (jm *Manager) SetEventManager(eventManager *events.EventManager) {
+	jm.lock.Lock()
+	defer jm.lock.Unlock()
+
+	jm.eventManager = eventManager
+}
+
+// callWithRetry 执行任务，根据任务的重试配置，失败时自动重试
+func (jm *Manager) callWithRetry(ctx context.Context, runner *jobRunner) error {
+	if runner.opts.retries <= 0 {
+		return jm.call(ctx, runner)
+	}
+
+	opts := append([]retry.Option{
+		retry.WithMaxRetries(runner.opts.retries),
+		retry.WithOnRetry(func(attempt int, err error, nextDelay time.Duration) {
+			log.Warningf("Job %s attempt %d failed, retry after %s: %s", runner.name, attempt, nextDelay, err)
+		}),
+	}, runner.opts.retryOptions...)
+
+	_, err := retry.Do(ctx, func(ctx context.Context, attempt int) (struct{}, error) {
+		return struct{}{}, jm.call(ctx, runner)
+	}, opts...)
+
+	return err
+}
+
+// notify 根据任务执行结果，判断是否需要触发失败或者恢复通知
+func (jm *Manager) notify(runner *jobRunner, err error, end time.Time) {
+	threshold := runner.opts.failureThreshold
+	if threshold <= 0 {
+		threshold = 1
+	}
+
+	runner.lock.Lock()
+	failures := runner.stats.consecutiveFailures
+	var failedEvt *JobFailedEvent
+	var recoveredEvt *JobRecoveredEvent
+	if err != nil && failures >= threshold && !runner.alerting {
+		runner.alerting = true
+		failedEvt = &JobFailedEvent{Name: runner.name, Error: err, ConsecutiveFailures: failures, Time: end}
+	} else if err == nil && runner.alerting {
+		runner.alerting = false
+		recoveredEvt = &JobRecoveredEvent{Name: runner.name, Failures: runner.alertFailures, Time: end}
+	}
+
+	if err != nil {
+		runner.alertFailures = failures
+	}
+	runner.lock.Unlock()
+
+	jm.lock.RLock()
+	eventManager := jm.eventManager
+	jm.lock.RUnlock()
+
+	if failedEvt != nil {
+		log.Errorf("Job %s failed %d times consecutively: %s", runner.name, failures, err)
+		if runner.opts.failureHandler != nil {
+			runner.opts.failureHandler(*failedEvt)
+		}
+		if eventManager != nil {
+			eventManager.Publish(*failedEvt)
+		}
+	}
+
+	if recoveredEvt != nil {
+		log.Infof("Job %s recovered after %d failures", runner.name, recoveredEvt.Failures)
+		if runner.opts.recoveryHandler != nil {
+			runner.opts.recoveryHandler(*recoveredEvt)
+		}
+		if eventManager != nil {
+			eventManager.Publish(*recoveredEvt)
+		}
+	}
+}
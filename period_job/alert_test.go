@@ -0,0 +1,100 @@
+package period_job_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mylxsw/container"
+	"github.com/mylxsw/go-toolkit/events"
+	"github.com/mylxsw/go-toolkit/failover/retry"
+	"github.com/mylxsw/go-toolkit/period_job"
+)
+
+func TestJobRetry(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	manager := period_job.NewManager(ctx, container.New())
+
+	var lock sync.Mutex
+	attempts := 0
+	_ = manager.Run("retry", func() error {
+		lock.Lock()
+		defer lock.Unlock()
+
+		attempts++
+		if attempts < 3 {
+			panic("sorry")
+		}
+		return nil
+	}, time.Hour, period_job.WithImmediately(), period_job.WithRetry(3, retry.WithBackoff(retry.ConstantBackoff(time.Millisecond))))
+
+	manager.Wait()
+
+	status, _ := manager.JobStatus("retry")
+	if attempts != 3 || status.RunCount != 1 || status.FailureCount != 0 {
+		t.Errorf("test failed, expect 3 attempts in 1 successful run, got attempts=%d, status=%+v", attempts, status)
+	}
+}
+
+func TestJobAlert(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	manager := period_job.NewManager(ctx, container.New())
+
+	var lock sync.Mutex
+	failedEvents := make([]period_job.JobFailedEvent, 0)
+	recoveredEvents := make([]period_job.JobRecoveredEvent, 0)
+	handled := 0
+
+	eventManager := events.NewEventManager(events.NewMemoryEventStore(false))
+	eventManager.Listen(func(evt period_job.JobFailedEvent) {
+		lock.Lock()
+		defer lock.Unlock()
+		failedEvents = append(failedEvents, evt)
+	})
+	eventManager.Listen(func(evt period_job.JobRecoveredEvent) {
+		lock.Lock()
+		defer lock.Unlock()
+		recoveredEvents = append(recoveredEvents, evt)
+	})
+	manager.SetEventManager(eventManager)
+
+	// 前 5 次执行失败，之后执行成功
+	runs := 0
+	_ = manager.Run("flaky", func() error {
+		lock.Lock()
+		defer lock.Unlock()
+
+		runs++
+		if runs <= 5 {
+			return errors.New("test error")
+		}
+		return nil
+	}, 10*time.Millisecond, period_job.WithFailureThreshold(3), period_job.WithFailureHandler(func(evt period_job.JobFailedEvent) {
+		lock.Lock()
+		defer lock.Unlock()
+		handled++
+	}))
+
+	manager.Wait()
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	if runs < 7 {
+		t.Fatalf("test failed, expect at least 7 runs, got %d", runs)
+	}
+
+	if len(failedEvents) != 1 || handled != 1 || failedEvents[0].ConsecutiveFailures != 3 || failedEvents[0].Name != "flaky" {
+		t.Errorf("test failed, expect one failed event at 3rd failure, got %+v (handled %d)", failedEvents, handled)
+	}
+
+	if len(recoveredEvents) != 1 || recoveredEvents[0].Failures != 5 {
+		t.Errorf("test failed, expect one recovered event after 5 failures, got %+v", recoveredEvents)
+	}
+}
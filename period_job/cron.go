@@ -0,0 +1,305 @@
+package period_job
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cronBounds cron 表达式中单个字段的取值范围
+type cronBounds struct {
+	min, max uint
+	names    map[string]uint
+}
+
+var (
+	secondBounds = cronBounds{min: 0, max: 59}
+	minuteBounds = cronBounds{min: 0, max: 59}
+	hourBounds   = cronBounds{min: 0, max: 23}
+	domBounds    = cronBounds{min: 1, max: 31}
+	monthBounds  = cronBounds{min: 1, max: 12, names: map[string]uint{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}}
+	dowBounds = cronBounds{min: 0, max: 7, names: map[string]uint{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}}
+)
+
+// cronDescriptors 预定义的 cron 表达式
+var cronDescriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// cronSchedule cron 表达式调度计划，每个字段使用位图表示允许的取值
+type cronSchedule struct {
+	second, minute, hour, dom, month, dow uint64
+	// domAny, dowAny 日期和星期字段是否为 * 或者 ?
+	domAny, dowAny bool
+	location       *time.Location
+}
+
+// Cron 解析 cron 表达式，支持以下格式
+//
+//	分 时 日 月 周                  标准的 5 字段格式
+//	秒 分 时 日 月 周               包含秒的 6 字段格式
+//	@yearly、@monthly、@weekly、@daily、@hourly 等预定义描述符
+//	@every 1h30m                   固定时间间隔
+//	CRON_TZ=Asia/Shanghai 0 8 * * * 以 CRON_TZ= 或者 TZ= 开头指定时区
+//
+// 没有指定时区时，使用 Next 参数中时间的时区
+func Cron(spec string) (Schedule, error) {
+	return CronInLocation(spec, nil)
+}
+
+// CronInLocation 解析 cron 表达式，使用 loc 作为默认时区
+func CronInLocation(spec string, loc *time.Location) (Schedule, error) {
+	spec = strings.TrimSpace(spec)
+	if strings.HasPrefix(spec, "CRON_TZ=") || strings.HasPrefix(spec, "TZ=") {
+		idx := strings.IndexAny(spec, " \t")
+		if idx < 0 {
+			return nil, fmt.Errorf("invalid cron spec %q: missing fields after time zone", spec)
+		}
+
+		tz := spec[strings.Index(spec, "=")+1 : idx]
+		location, err := time.LoadLocation(tz)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cron spec %q: %s", spec, err)
+		}
+
+		loc = location
+		spec = strings.TrimSpace(spec[idx:])
+	}
+
+	if strings.HasPrefix(spec, "@every ") {
+		interval, err := time.ParseDuration(strings.TrimSpace(spec[len("@every "):]))
+		if err != nil {
+			return nil, fmt.Errorf("invalid cron spec %q: %s", spec, err)
+		}
+		if interval <= 0 {
+			return nil, fmt.Errorf("invalid cron spec %q: interval must be greater than 0", spec)
+		}
+
+		return Every(interval), nil
+	}
+
+	if descriptor, ok := cronDescriptors[strings.ToLower(spec)]; ok {
+		spec = descriptor
+	}
+
+	fields := strings.Fields(spec)
+	switch len(fields) {
+	case 5:
+		fields = append([]string{"0"}, fields...)
+	case 6:
+	default:
+		return nil, fmt.Errorf("invalid cron spec %q: expect 5 or 6 fields, got %d", spec, len(fields))
+	}
+
+	schedule := &cronSchedule{location: loc}
+
+	var err error
+	parsers := []struct {
+		target *uint64
+		bounds cronBounds
+		name   string
+	}{
+		{&schedule.second, secondBounds, "second"},
+		{&schedule.minute, minuteBounds, "minute"},
+		{&schedule.hour, hourBounds, "hour"},
+		{&schedule.dom, domBounds, "day of month"},
+		{&schedule.month, monthBounds, "month"},
+		{&schedule.dow, dowBounds, "day of week"},
+	}
+	for i, p := range parsers {
+		if *p.target, err = parseCronField(fields[i], p.bounds); err != nil {
+			return nil, fmt.Errorf("invalid cron spec %q: %s field: %s", spec, p.name, err)
+		}
+	}
+
+	// 星期字段中 7 与 0 都表示星期日
+	if schedule.dow&(1<<7) > 0 {
+		schedule.dow |= 1
+	}
+
+	schedule.domAny = fields[3] == "*" || fields[3] == "?"
+	schedule.dowAny = fields[5] == "*" || fields[5] == "?"
+
+	return schedule, nil
+}
+
+// MustCron 解析 cron 表达式，解析失败时 panic
+func MustCron(spec string) Schedule {
+	schedule, err := Cron(spec)
+	if err != nil {
+		panic(err)
+	}
+
+	return schedule
+}
+
+// parseCronField 解析单个字段，字段由逗号分隔的多个范围组成
+func parseCronField(field string, bounds cronBounds) (uint64, error) {
+	var bits uint64
+	for _, expr := range strings.Split(field, ",") {
+		b, err := parseCronRange(expr, bounds)
+		if err != nil {
+			return 0, err
+		}
+
+		bits |= b
+	}
+
+	return bits, nil
+}
+
+// parseCronRange 解析 *、?、a、a-b、*/n、a/n、a-b/n 格式的范围
+func parseCronRange(expr string, bounds cronBounds) (uint64, error) {
+	rangeAndStep := strings.Split(expr, "/")
+	if len(rangeAndStep) > 2 {
+		return 0, fmt.Errorf("invalid expression %q", expr)
+	}
+
+	var start, end uint
+	lowAndHigh := strings.Split(rangeAndStep[0], "-")
+	switch {
+	case rangeAndStep[0] == "*" || rangeAndStep[0] == "?":
+		start, end = bounds.min, bounds.max
+	case len(lowAndHigh) == 1:
+		val, err := parseCronValue(lowAndHigh[0], bounds)
+		if err != nil {
+			return 0, err
+		}
+
+		start, end = val, val
+		if len(rangeAndStep) == 2 {
+			end = bounds.max
+		}
+	case len(lowAndHigh) == 2:
+		var err error
+		if start, err = parseCronValue(lowAndHigh[0], bounds); err != nil {
+			return 0, err
+		}
+		if end, err = parseCronValue(lowAndHigh[1], bounds); err != nil {
+			return 0, err
+		}
+	default:
+		return 0, fmt.Errorf("invalid expression %q", expr)
+	}
+
+	if start > end {
+		return 0, fmt.Errorf("invalid range %q: beginning of range is greater than end", expr)
+	}
+
+	step := uint(1)
+	if len(rangeAndStep) == 2 {
+		s, err := strconv.ParseUint(rangeAndStep[1], 10, 8)
+		if err != nil || s == 0 {
+			return 0, fmt.Errorf("invalid step %q", rangeAndStep[1])
+		}
+
+		step = uint(s)
+	}
+
+	var bits uint64
+	for i := start; i <= end; i += step {
+		bits |= 1 << i
+	}
+
+	return bits, nil
+}
+
+// parseCronValue 解析单个数值或者名称（例如 JAN、MON）
+func parseCronValue(val string, bounds cronBounds) (uint, error) {
+	if bounds.names != nil {
+		if v, ok := bounds.names[strings.ToLower(val)]; ok {
+			return v, nil
+		}
+	}
+
+	v, err := strconv.ParseUint(val, 10, 8)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value %q", val)
+	}
+
+	if uint(v) < bounds.min || uint(v) > bounds.max {
+		return 0, fmt.Errorf("value %d out of range [%d, %d]", v, bounds.min, bounds.max)
+	}
+
+	return uint(v), nil
+}
+
+// Next 返回 t 之后的下一次执行时间，5 年内找不到匹配的时间时返回零值
+func (s *cronSchedule) Next(t time.Time) time.Time {
+	origLocation := t.Location()
+	loc := s.location
+	if loc == nil {
+		loc = origLocation
+	}
+
+	t = t.In(loc)
+	t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second()+1, 0, loc)
+
+	yearLimit := t.Year() + 5
+
+WRAP:
+	if t.Year() > yearLimit {
+		return time.Time{}
+	}
+
+	for s.month&(1<<uint(t.Month())) == 0 {
+		t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+		if t.Month() == time.January {
+			goto WRAP
+		}
+	}
+
+	for !s.dayMatches(t) {
+		t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+		if t.Day() == 1 {
+			goto WRAP
+		}
+	}
+
+	for s.hour&(1<<uint(t.Hour())) == 0 {
+		t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+		if t.Hour() == 0 {
+			goto WRAP
+		}
+	}
+
+	for s.minute&(1<<uint(t.Minute())) == 0 {
+		t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute()+1, 0, 0, loc)
+		if t.Minute() == 0 {
+			goto WRAP
+		}
+	}
+
+	for s.second&(1<<uint(t.Second())) == 0 {
+		t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second()+1, 0, loc)
+		if t.Second() == 0 {
+			goto WRAP
+		}
+	}
+
+	return t.In(origLocation)
+}
+
+// dayMatches 日期与星期字段都有限制时，满足任意一个即可（与标准 cron 行为一致）
+func (s *cronSchedule) dayMatches(t time.Time) bool {
+	domMatch := s.dom&(1<<uint(t.Day())) > 0
+	dowMatch := s.dow&(1<<uint(t.Weekday())) > 0
+
+	if s.domAny || s.dowAny {
+		return domMatch && dowMatch
+	}
+
+	return domMatch || dowMatch
+}
@@ -0,0 +1,86 @@
+package period_job_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/mylxsw/container"
+	"github.com/mylxsw/go-toolkit/period_job"
+)
+
+func TestCronNext(t *testing.T) {
+	base := time.Date(2019, 12, 31, 23, 59, 30, 0, time.UTC)
+
+	testcases := []struct {
+		spec   string
+		expect time.Time
+	}{
+		{"* * * * *", time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{"*/15 * * * * *", time.Date(2019, 12, 31, 23, 59, 45, 0, time.UTC)},
+		{"30 8 * * mon-fri", time.Date(2020, 1, 1, 8, 30, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2020, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{"0 12 1 * 7", time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)},
+		{"0 12 15 * SUN", time.Date(2020, 1, 5, 12, 0, 0, 0, time.UTC)},
+		{"0 9-17/4 * JAN,mar ?", time.Date(2020, 1, 1, 9, 0, 0, 0, time.UTC)},
+		{"@daily", time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{"@yearly", time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{"@weekly", time.Date(2020, 1, 5, 0, 0, 0, 0, time.UTC)},
+		{"@every 1h", time.Date(2020, 1, 1, 0, 59, 30, 0, time.UTC)},
+		{"CRON_TZ=Asia/Shanghai 0 8 * * *", time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tc := range testcases {
+		schedule, err := period_job.Cron(tc.spec)
+		if err != nil {
+			t.Errorf("parse %s failed: %s", tc.spec, err)
+			continue
+		}
+
+		if next := schedule.Next(base); !next.Equal(tc.expect) {
+			t.Errorf("test failed for %s, expect %s, got %s", tc.spec, tc.expect, next)
+		}
+	}
+}
+
+func TestCronInvalid(t *testing.T) {
+	for _, spec := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "5-1 * * * *", "*/0 * * * *", "* * * foo *", "TZ=Nowhere/Land * * * * *", "@every abc"} {
+		if _, err := period_job.Cron(spec); err == nil {
+			t.Errorf("test failed, expect error for %q", spec)
+		}
+	}
+}
+
+func TestRunCron(t *testing.T) {
+	cc := container.New()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2500*time.Millisecond)
+	defer cancel()
+
+	manager := period_job.NewManager(ctx, cc)
+
+	job := &DemoJob{}
+	if err := manager.RunCron("Cron", job, "* * * * * *"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if err := manager.RunCron("Invalid", job, "* * *"); err == nil {
+		t.Error("test failed, expect error for invalid spec")
+	}
+
+	time.Sleep(10 * time.Millisecond)
+	next, ok := manager.NextRun("Cron")
+	if !ok || next.Nanosecond() != 0 || time.Until(next) > time.Second {
+		t.Errorf("test failed, invalid next run %s", next)
+	}
+
+	manager.Wait()
+
+	if job.Count() < 2 {
+		t.Errorf("test failed, expect at least 2 runs, got %d", job.Count())
+	}
+
+	if _, ok := manager.NextRun("Cron"); ok {
+		t.Error("test failed, stopped job should not have next run")
+	}
+}
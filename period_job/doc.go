@@ -9,6 +9,37 @@ Package period_job 实现了周期性任务的定时触发执行。
 	manager.Run("Test", job1, 10*time.Millisecond)
 	manager.Run("Test2", job2, 20*time.Millisecond)
 
+	// 使用 cron 表达式，每天 8 点（上海时区）执行
+	if err := manager.RunCron("Report", &ReportJob{}, "CRON_TZ=Asia/Shanghai 0 8 * * *"); err != nil {
+		panic(err)
+	}
+
+	// 任务也可以是一个函数，参数由容器注入，上一次执行未结束时排队等待，单次执行最长 30s
+	manager.Run("Sync", func(ctx context.Context, repo *Repo) error {
+		return repo.Sync(ctx)
+	}, time.Minute, period_job.WithOverlap(period_job.OverlapQueue), period_job.WithTimeout(30*time.Second))
+
+	next, _ := manager.NextRun("Report")
+	fmt.Printf("report job will run at %s\n", next)
+
+	// 多实例部署时，通过共享目录上的文件锁保证每次调度只在一个实例上执行
+	locker, _ := period_job.NewFileLocker("/mnt/shared/locks")
+	manager.SetLocker(locker, 30*time.Second)
+
+	// 持久化任务执行时间，重启后补执行一次停止期间错过的调度
+	store, _ := period_job.NewFileStore("/var/lib/app/jobs.json")
+	manager.SetStore(store)
+	_ = manager.RunCron("Daily", &DailyJob{}, "@daily", period_job.WithMisfire(period_job.MisfireRunOnce))
+
+	// 失败时重试 2 次，连续失败 3 次后发布 JobFailedEvent 事件，恢复后发布 JobRecoveredEvent 事件
+	manager.SetEventManager(eventManager)
+	_ = manager.Run("Notify", &NotifyJob{}, time.Minute, period_job.WithRetry(2), period_job.WithFailureThreshold(3))
+
+	// 运行时动态调整任务
+	_ = manager.Reschedule("Test", time.Second)
+	_ = manager.TriggerNow("Test2")
+	_ = manager.Remove("Test2")
+
 	manager.Wait()
 
  */
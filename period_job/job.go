@@ -2,16 +2,56 @@ package period_job
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"reflect"
 	"sync"
 	"time"
 
 	"github.com/mylxsw/asteria/log"
 	"github.com/mylxsw/container"
+	"github.com/mylxsw/go-toolkit/events"
 )
 
-// Job is a interface for a job
-type Job interface {
-	Handle()
+var (
+	// ErrTimeout 任务执行超时
+	ErrTimeout = errors.New("job execution timeout")
+	// ErrJobExists 同名任务已经存在
+	ErrJobExists = errors.New("job already exists")
+	// ErrJobNotFound 任务不存在
+	ErrJobNotFound = errors.New("job not found")
+	// ErrSkipped 任务仍在执行，根据重叠策略跳过了本次执行
+	ErrSkipped = errors.New("job is still running, skipped")
+)
+
+// Job is a job to execute periodically
+//
+// Job 可以是一个包含 Handle 方法的对象，也可以直接是一个函数。Handle 方法（或者函数）的参数由容器自动注入，
+// 声明 context.Context 类型的参数可以获取本次执行的上下文（执行超时或者 Manager 停止时会被取消），
+// 返回值可以为空，也可以返回一个 error 表示执行失败
+type Job interface{}
+
+// jobRunner 任务运行时信息
+type jobRunner struct {
+	name     string
+	handler  interface{}
+	schedule Schedule
+	opts     jobOptions
+
+	ctx     context.Context
+	cancel  context.CancelFunc
+	changed chan struct{}
+
+	lock       sync.Mutex
+	running    int
+	queuedTick time.Time
+	// queuedManual 排队等待的执行是否为手动触发
+	queuedManual bool
+	stats        jobStats
+
+	// alerting 是否已经触发了失败通知，alertFailures 为最近一次失败时的连续失败次数
+	alerting      bool
+	alertFailures int
 }
 
 // Manager 周期性任务管理器
@@ -19,7 +59,13 @@ type Manager struct {
 	container container.Container
 	ctx       context.Context
 	pauseJobs map[string]bool
-	lock      sync.RWMutex
+	jobs      map[string]*jobRunner
+	locker    Locker
+	lockTTL   time.Duration
+	store     Store
+
+	eventManager *events.EventManager
+	lock         sync.RWMutex
 
 	wg sync.WaitGroup
 }
@@ -30,6 +76,7 @@ func NewManager(ctx context.Context, cc container.Container) *Manager {
 		container: cc,
 		ctx:       ctx,
 		pauseJobs: make(map[string]bool),
+		jobs:      make(map[string]*jobRunner),
 	}
 }
 
@@ -49,42 +96,259 @@ func (jm *Manager) Pause(name string, pause bool) {
 	jm.pauseJobs[name] = pause
 }
 
-// Run 启动周期性任务循环
-func (jm *Manager) Run(name string, job Job, interval time.Duration) {
+// NextRun 返回任务的下一次执行时间，任务不存在或者已经停止时返回 false
+func (jm *Manager) NextRun(name string) (time.Time, bool) {
+	jm.lock.RLock()
+	runner, ok := jm.jobs[name]
+	jm.lock.RUnlock()
+
+	if !ok {
+		return time.Time{}, false
+	}
+
+	next := runner.nextRun()
+	return next, !next.IsZero()
+}
+
+// Run 启动周期性任务循环，每隔 interval 执行一次，同名任务已经存在时返回 ErrJobExists
+func (jm *Manager) Run(name string, job Job, interval time.Duration, opts ...JobOption) error {
+	return jm.RunSchedule(name, job, Every(interval), opts...)
+}
+
+// RunCron 启动周期性任务循环，按照 cron 表达式执行，表达式格式参考 Cron 函数
+func (jm *Manager) RunCron(name string, job Job, spec string, opts ...JobOption) error {
+	schedule, err := Cron(spec)
+	if err != nil {
+		return err
+	}
+
+	return jm.RunSchedule(name, job, schedule, opts...)
+}
+
+// RunSchedule 启动周期性任务循环，按照 schedule 指定的调度计划执行
+func (jm *Manager) RunSchedule(name string, job Job, schedule Schedule, opts ...JobOption) error {
+	handler, err := resolveHandler(job)
+	if err != nil {
+		return err
+	}
+
+	runner := &jobRunner{
+		name:     name,
+		handler:  handler,
+		schedule: schedule,
+		changed:  make(chan struct{}, 1),
+	}
+	for _, opt := range opts {
+		opt(&runner.opts)
+	}
+
+	jm.lock.Lock()
+	if _, ok := jm.jobs[name]; ok {
+		jm.lock.Unlock()
+		return fmt.Errorf("%w: %s", ErrJobExists, name)
+	}
+
+	runner.ctx, runner.cancel = context.WithCancel(jm.ctx)
+	jm.jobs[name] = runner
+	jm.lock.Unlock()
+
 	log.Debugf("Job %s running...", name)
 
 	jm.wg.Add(1)
+	go jm.loop(runner)
+
+	return nil
+}
+
+// loop 任务调度循环，直到任务被移除或者 Manager 停止
+func (jm *Manager) loop(runner *jobRunner) {
+	defer func() {
+		runner.setNextRun(time.Time{})
+		jm.wg.Done()
+	}()
+
+	schedule := runner.currentSchedule()
+	if !jm.Paused(runner.name) {
+		jm.catchUp(runner, schedule)
+	}
 
+	if runner.opts.immediately && !jm.Paused(runner.name) {
+		jm.dispatch(runner, time.Now(), false)
+	}
+
+	next := schedule.Next(time.Now())
+	for {
+		runner.setNextRun(next)
+
+		// 调度计划没有后续执行时间时，仍然需要响应 Reschedule 和停止信号
+		var timeout <-chan time.Time
+		var timer *time.Timer
+		if !next.IsZero() {
+			timer = time.NewTimer(time.Until(next))
+			timeout = timer.C
+		} else {
+			log.Debugf("Job %s has no more scheduled run", runner.name)
+		}
+
+		select {
+		case <-timeout:
+			if !jm.Paused(runner.name) {
+				jm.dispatch(runner, next, false)
+			}
+
+			// 调度被阻塞，错过了下一次执行时间时，跳过错过的执行
+			now := time.Now()
+			if next = schedule.Next(next); !next.IsZero() && next.Before(now) {
+				next = schedule.Next(now)
+			}
+		case <-runner.changed:
+			if timer != nil {
+				timer.Stop()
+			}
+
+			schedule = runner.currentSchedule()
+			next = schedule.Next(time.Now())
+		case <-runner.ctx.Done():
+			if timer != nil {
+				timer.Stop()
+			}
+
+			log.Debugf("Job %s stopped", runner.name)
+			return
+		}
+	}
+}
+
+// resolveHandler 获取任务的执行函数，job 必须是函数，或者包含 Handle 方法
+func resolveHandler(job Job) (interface{}, error) {
+	jobValue := reflect.ValueOf(job)
+	if !jobValue.IsValid() {
+		return nil, errors.New("job must not be nil")
+	}
+
+	if jobValue.Kind() == reflect.Func {
+		return job, nil
+	}
+
+	handler := jobValue.MethodByName("Handle")
+	if !handler.IsValid() {
+		return nil, fmt.Errorf("job %s must be a function or has a Handle method", jobValue.Type())
+	}
+
+	return handler.Interface(), nil
+}
+
+// dispatch 根据任务的重叠策略，异步执行任务，tick 为本次执行对应的调度时间，manual 表示手动触发。
+// 根据重叠策略跳过本次执行时返回 false
+func (jm *Manager) dispatch(runner *jobRunner, tick time.Time, manual bool) bool {
+	runner.lock.Lock()
+	if runner.running > 0 {
+		switch runner.opts.overlap {
+		case OverlapSkip:
+			runner.lock.Unlock()
+			log.Warningf("Job %s is still running, skipped", runner.name)
+			return false
+		case OverlapQueue:
+			defer runner.lock.Unlock()
+			if !runner.queuedTick.IsZero() {
+				log.Warningf("Job %s is still running and already queued, skipped", runner.name)
+				return false
+			}
+
+			runner.queuedTick, runner.queuedManual = tick, manual
+			return true
+		}
+	}
+
+	runner.running++
+	runner.lock.Unlock()
+
+	jm.wg.Add(1)
 	go func() {
-		globalTicker := time.NewTicker(interval)
-		defer func() {
-			globalTicker.Stop()
-			jm.wg.Done()
-		}()
+		defer jm.wg.Done()
 
 		for {
-			select {
-			case <-globalTicker.C:
-				if jm.Paused(name) {
-					continue
-				}
-
-				func() {
-					defer func() {
-						if err := recover(); err != nil {
-							log.Errorf("Job %s has some error：%s", name, err)
-						}
-					}()
-					if err := jm.container.Resolve(job.Handle); err != nil {
-						log.Errorf("Job %s failed: %s", name, err)
-					}
-				}()
-			case <-jm.ctx.Done():
-				log.Debugf("Job %s stopped", name)
-				return
+			_ = jm.execute(runner, tick, manual)
+
+			runner.lock.Lock()
+			if !runner.queuedTick.IsZero() && runner.ctx.Err() == nil {
+				tick, manual = runner.queuedTick, runner.queuedManual
+				runner.queuedTick, runner.queuedManual = time.Time{}, false
+				runner.lock.Unlock()
+				continue
 			}
+
+			runner.queuedTick, runner.queuedManual = time.Time{}, false
+			runner.running--
+			runner.lock.Unlock()
+			return
 		}
 	}()
+
+	return true
+}
+
+// execute 执行一次任务，设置了分布式锁时，获取锁失败则跳过本次执行。
+// 手动触发的执行不属于任何调度周期，不获取分布式锁，否则会和同一周期的调度执行相互冲突
+func (jm *Manager) execute(runner *jobRunner, tick time.Time, manual bool) error {
+	ctx, cancel := context.WithCancel(runner.ctx)
+	if runner.opts.timeout > 0 {
+		ctx, cancel = context.WithTimeout(runner.ctx, runner.opts.timeout)
+	}
+	defer cancel()
+
+	release := func() {}
+	var err error
+	if !manual {
+		release, err = jm.acquireLock(ctx, cancel, runner, tick)
+	}
+	if err != nil {
+		if err == ErrLocked {
+			log.Debugf("Job %s at %s is locked by another instance, skipped", runner.name, tick)
+		} else {
+			log.Errorf("Job %s acquire lock failed, skipped: %s", runner.name, err)
+		}
+
+		return err
+	}
+	defer release()
+
+	startTime := time.Now()
+	runner.recordStart(startTime)
+
+	// 执行超时、任务被移除、Manager 停止或者锁续租失败时 ctx 会被取消，仍然等待任务响应取消后结束，
+	// 任务真正结束之前不释放锁，也不会开始新的执行
+	err = jm.callWithRetry(ctx, runner)
+	if ctx.Err() == context.DeadlineExceeded && runner.ctx.Err() == nil {
+		err = ErrTimeout
+	}
+
+	endTime := time.Now()
+	runner.recordEnd(startTime, endTime, err)
+	jm.saveLastRun(runner, tick)
+	jm.notify(runner, err, endTime)
+
+	if err != nil {
+		log.Errorf("Job %s failed: %s", runner.name, err)
+	}
+
+	return err
+}
+
+// call 通过容器调用任务的执行函数，context.Context 参数注入为本次执行的上下文
+func (jm *Manager) call(ctx context.Context, runner *jobRunner) (err error) {
+	defer func() {
+		if err2 := recover(); err2 != nil {
+			err = fmt.Errorf("job %s has some error: %v", runner.name, err2)
+		}
+	}()
+
+	cc := container.Extend(jm.container)
+	cc.MustSingleton(func() context.Context {
+		return ctx
+	})
+
+	return cc.ResolveWithError(runner.handler)
 }
 
 // Wait 等待所有任务结束
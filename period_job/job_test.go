@@ -57,3 +57,135 @@ func TestJob(t *testing.T) {
 		t.Error("test failed")
 	}
 }
+
+func TestJobOverlapPolicy(t *testing.T) {
+	cc := container.New()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	manager := period_job.NewManager(ctx, cc)
+
+	var lock sync.Mutex
+	concurrent, maxConcurrent := map[string]int{}, map[string]int{}
+	counts := map[string]int{}
+
+	slowJob := func(name string) func() {
+		return func() {
+			lock.Lock()
+			concurrent[name]++
+			counts[name]++
+			if concurrent[name] > maxConcurrent[name] {
+				maxConcurrent[name] = concurrent[name]
+			}
+			lock.Unlock()
+
+			time.Sleep(35 * time.Millisecond)
+
+			lock.Lock()
+			concurrent[name]--
+			lock.Unlock()
+		}
+	}
+
+	manager.Run("skip", slowJob("skip"), 10*time.Millisecond)
+	manager.Run("queue", slowJob("queue"), 10*time.Millisecond, period_job.WithOverlap(period_job.OverlapQueue))
+	manager.Run("allow", slowJob("allow"), 10*time.Millisecond, period_job.WithOverlap(period_job.OverlapAllow))
+
+	manager.Wait()
+
+	if maxConcurrent["skip"] != 1 || maxConcurrent["queue"] != 1 {
+		t.Errorf("test failed, skip and queue policy should not run concurrently, got %v", maxConcurrent)
+	}
+
+	if maxConcurrent["allow"] < 2 {
+		t.Errorf("test failed, allow policy should run concurrently, got %v", maxConcurrent)
+	}
+
+	if counts["queue"] <= counts["skip"] {
+		t.Errorf("test failed, queue policy should run more than skip policy, got %v", counts)
+	}
+}
+
+func TestJobTimeoutAndContext(t *testing.T) {
+	cc := container.New()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 150*time.Millisecond)
+	defer cancel()
+
+	manager := period_job.NewManager(ctx, cc)
+
+	var lock sync.Mutex
+	timeouts := 0
+	immediately := make(chan time.Time, 10)
+
+	manager.Run("timeout", func(ctx context.Context) error {
+		select {
+		case <-time.After(time.Second):
+			return nil
+		case <-ctx.Done():
+			lock.Lock()
+			timeouts++
+			lock.Unlock()
+			return ctx.Err()
+		}
+	}, 50*time.Millisecond, period_job.WithTimeout(10*time.Millisecond))
+
+	manager.Run("immediately", func() {
+		immediately <- time.Now()
+	}, time.Hour, period_job.WithImmediately())
+
+	manager.Wait()
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	if timeouts < 2 {
+		t.Errorf("test failed, expect at least 2 timeouts, got %d", timeouts)
+	}
+
+	if len(immediately) != 1 {
+		t.Errorf("test failed, expect job run immediately once, got %d", len(immediately))
+	}
+}
+
+func TestJobTimeoutWaitsForHandler(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	manager := period_job.NewManager(ctx, container.New())
+
+	var lock sync.Mutex
+	running, maxRunning, finished := 0, 0, 0
+
+	// 任务超时后仍然需要一段时间才能结束，结束之前不能开始新的执行
+	manager.Run("slow", func(ctx context.Context) {
+		lock.Lock()
+		running++
+		if running > maxRunning {
+			maxRunning = running
+		}
+		lock.Unlock()
+
+		<-ctx.Done()
+		time.Sleep(50 * time.Millisecond)
+
+		lock.Lock()
+		running--
+		finished++
+		lock.Unlock()
+	}, 10*time.Millisecond, period_job.WithTimeout(10*time.Millisecond), period_job.WithOverlap(period_job.OverlapQueue))
+
+	manager.Wait()
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	if maxRunning != 1 {
+		t.Errorf("test failed, expect timed out job not overlapped, got %d concurrent runs", maxRunning)
+	}
+
+	if running != 0 || finished == 0 {
+		t.Errorf("test failed, expect Wait returns after all runs finished, running %d, finished %d", running, finished)
+	}
+}
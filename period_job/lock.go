@@ -0,0 +1,108 @@
+package period_job
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/mylxsw/asteria/log"
+)
+
+// DefaultLockTTL 默认的锁租约时间
+const DefaultLockTTL = 30 * time.Second
+
+// ErrLocked 锁已经被其它实例持有，或者本次调度已经在其它实例上执行过
+var ErrLocked = errors.New("job is locked by another instance")
+
+// Locker 分布式锁，多实例部署时，保证任务的每一次调度只在一个实例上执行
+type Locker interface {
+	// Lock 为任务 name 在调度时间 tick 的执行获取锁，ttl 为锁的租约时间，租约到期前需要续租，
+	// 锁已经被其它实例持有，或者该次调度已经执行过时返回 ErrLocked。
+	// tick 已经对齐到调度周期（固定间隔任务为间隔的整数倍），各实例同一次调度的 tick 相同
+	Lock(ctx context.Context, name string, tick time.Time, ttl time.Duration) (Lease, error)
+}
+
+// Lease 已经获取到的锁
+type Lease interface {
+	// Renew 续租，任务执行时间较长时会被定期调用
+	Renew(ttl time.Duration) error
+	// Release 释放锁
+	Release() error
+}
+
+// LockerFunc 使用函数实现 Locker 接口，用于对接 Redis、etcd 等外部存储
+type LockerFunc func(ctx context.Context, name string, tick time.Time, ttl time.Duration) (Lease, error)
+
+// Lock 获取锁
+func (f LockerFunc) Lock(ctx context.Context, name string, tick time.Time, ttl time.Duration) (Lease, error) {
+	return f(ctx, name, tick, ttl)
+}
+
+// SetLocker 设置分布式锁，设置后所有任务（除了使用 WithoutLocker 选项的任务）执行前都需要先获取锁，
+// ttl 为锁的租约时间，任务执行期间每隔 ttl/3 续租一次，ttl 为 0 时使用 DefaultLockTTL
+func (jm *Manager) SetLocker(locker Locker, ttl time.Duration) {
+	jm.lock.Lock()
+	defer jm.lock.Unlock()
+
+	if ttl <= 0 {
+		ttl = DefaultLockTTL
+	}
+
+	jm.locker = locker
+	jm.lockTTL = ttl
+}
+
+// WithoutLocker 任务不使用分布式锁，每个实例都会执行
+func WithoutLocker() JobOption {
+	return func(opts *jobOptions) {
+		opts.withoutLocker = true
+	}
+}
+
+// acquireLock 为任务获取锁，返回释放锁的函数，没有设置分布式锁时直接返回
+// 获取锁之后，会定期续租，续租失败时取消 cancel 对应的任务执行上下文
+func (jm *Manager) acquireLock(ctx context.Context, cancel context.CancelFunc, runner *jobRunner, tick time.Time) (func(), error) {
+	jm.lock.RLock()
+	locker, ttl := jm.locker, jm.lockTTL
+	jm.lock.RUnlock()
+
+	if locker == nil || runner.opts.withoutLocker {
+		return func() {}, nil
+	}
+
+	lease, err := locker.Lock(ctx, runner.name, lockPeriod(runner.currentSchedule(), tick), ttl)
+	if err != nil {
+		return nil, err
+	}
+
+	stopRenew := make(chan struct{})
+	renewStopped := make(chan struct{})
+	go func() {
+		defer close(renewStopped)
+
+		ticker := time.NewTicker(ttl / 3)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				if err := lease.Renew(ttl); err != nil {
+					log.Errorf("Job %s renew lock failed, cancel execution: %s", runner.name, err)
+					cancel()
+					return
+				}
+			case <-stopRenew:
+				return
+			}
+		}
+	}()
+
+	return func() {
+		close(stopRenew)
+		<-renewStopped
+
+		if err := lease.Release(); err != nil {
+			log.Warningf("Job %s release lock failed: %s", runner.name, err)
+		}
+	}, nil
+}
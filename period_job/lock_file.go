@@ -0,0 +1,95 @@
+package period_job
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+var lockFileNameRegexp = regexp.MustCompile(`[^a-zA-Z0-9_.-]`)
+
+// FileLocker 基于文件锁（flock）的 Locker 实现，多个实例需要共享同一个锁目录
+//
+// 每个任务对应锁目录下的一个 <name>.lock 文件，文件第一行记录最近一次执行的调度时间，
+// 第二行记录持有锁的主机名和进程 ID，与 pidfile 类似。持有锁的进程退出后，锁会被自动释放
+type FileLocker struct {
+	dir string
+}
+
+// NewFileLocker 创建一个基于文件锁的 Locker，dir 为锁文件所在的目录，不存在时自动创建
+func NewFileLocker(dir string) (*FileLocker, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	return &FileLocker{dir: dir}, nil
+}
+
+// Lock 获取锁，锁文件已经被锁定，或者锁文件中记录的调度时间不早于 tick 时返回 ErrLocked
+func (locker *FileLocker) Lock(ctx context.Context, name string, tick time.Time, ttl time.Duration) (Lease, error) {
+	path := filepath.Join(locker.dir, lockFileNameRegexp.ReplaceAllString(name, "_")+".lock")
+
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := flock(f); err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+
+	lease := &fileLease{file: f}
+
+	content, err := ioutil.ReadAll(f)
+	if err != nil {
+		_ = lease.Release()
+		return nil, err
+	}
+
+	if lines := strings.SplitN(string(content), "\n", 2); len(lines) > 0 && lines[0] != "" {
+		if last, err := time.Parse(time.RFC3339Nano, strings.TrimSpace(lines[0])); err == nil && !last.Before(tick) {
+			_ = lease.Release()
+			return nil, ErrLocked
+		}
+	}
+
+	hostname, _ := os.Hostname()
+	if err := f.Truncate(0); err != nil {
+		_ = lease.Release()
+		return nil, err
+	}
+
+	if _, err := f.WriteAt([]byte(fmt.Sprintf("%s\n%s %d\n", tick.Format(time.RFC3339Nano), hostname, os.Getpid())), 0); err != nil {
+		_ = lease.Release()
+		return nil, err
+	}
+
+	return lease, nil
+}
+
+// fileLease 基于文件锁的租约
+type fileLease struct {
+	file *os.File
+}
+
+// Renew 文件锁在进程退出前一直有效，续租只更新锁文件的修改时间，便于排查问题
+func (lease *fileLease) Renew(ttl time.Duration) error {
+	now := time.Now()
+	return os.Chtimes(lease.file.Name(), now, now)
+}
+
+// Release 释放锁
+func (lease *fileLease) Release() error {
+	if err := funlock(lease.file); err != nil {
+		_ = lease.file.Close()
+		return err
+	}
+
+	return lease.file.Close()
+}
@@ -0,0 +1,19 @@
+//go:build windows
+// +build windows
+
+package period_job
+
+import (
+	"errors"
+	"os"
+)
+
+// flock windows 暂不支持文件锁
+func flock(f *os.File) error {
+	return errors.New("file locker is not supported on windows")
+}
+
+// funlock 释放文件锁
+func funlock(f *os.File) error {
+	return nil
+}
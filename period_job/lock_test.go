@@ -0,0 +1,245 @@
+package period_job_test
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mylxsw/container"
+	"github.com/mylxsw/go-toolkit/period_job"
+)
+
+func TestFileLocker(t *testing.T) {
+	dir, err := ioutil.TempDir("", "period_job_lock")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2500*time.Millisecond)
+	defer cancel()
+
+	var lock sync.Mutex
+	runs := make(map[int]int)
+
+	managers := make([]*period_job.Manager, 0)
+	for i := 0; i < 3; i++ {
+		locker, err := period_job.NewFileLocker(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		manager := period_job.NewManager(ctx, container.New())
+		manager.SetLocker(locker, time.Second)
+
+		_ = manager.RunCron("Locked", func() {
+			lock.Lock()
+			runs[time.Now().Second()]++
+			lock.Unlock()
+
+			time.Sleep(100 * time.Millisecond)
+		}, "* * * * * *")
+
+		managers = append(managers, manager)
+	}
+
+	for _, manager := range managers {
+		manager.Wait()
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	if len(runs) < 2 {
+		t.Errorf("test failed, expect at least 2 ticks, got %v", runs)
+	}
+
+	for second, count := range runs {
+		if count != 1 {
+			t.Errorf("test failed, tick at %d executed %d times", second, count)
+		}
+	}
+}
+
+func TestFileLockerInterval(t *testing.T) {
+	dir, err := ioutil.TempDir("", "period_job_lock")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// 两个实例相隔 50ms 启动，各自按照自己的启动时间调度，同一个周期内只能执行一次
+	interval := 300 * time.Millisecond
+	time.Sleep(time.Until(time.Now().Truncate(interval).Add(interval + 20*time.Millisecond)))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 1000*time.Millisecond)
+	defer cancel()
+
+	var lock sync.Mutex
+	runs := make(map[time.Time]int)
+
+	managers := make([]*period_job.Manager, 0)
+	for i := 0; i < 2; i++ {
+		if i > 0 {
+			time.Sleep(50 * time.Millisecond)
+		}
+
+		locker, err := period_job.NewFileLocker(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		manager := period_job.NewManager(ctx, container.New())
+		manager.SetLocker(locker, time.Second)
+
+		_ = manager.Run("Interval", func() {
+			lock.Lock()
+			runs[time.Now().Truncate(interval)]++
+			lock.Unlock()
+		}, interval, period_job.WithImmediately())
+
+		managers = append(managers, manager)
+	}
+
+	for _, manager := range managers {
+		manager.Wait()
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	if len(runs) < 3 {
+		t.Errorf("test failed, expect at least 3 periods, got %v", runs)
+	}
+
+	for period, count := range runs {
+		if count != 1 {
+			t.Errorf("test failed, period %s executed %d times", period.Format("15:04:05.000"), count)
+		}
+	}
+}
+
+type testLease struct {
+	lock    *sync.Mutex
+	renewed *int
+	fail    bool
+}
+
+func (lease testLease) Renew(ttl time.Duration) error {
+	lease.lock.Lock()
+	defer lease.lock.Unlock()
+
+	*lease.renewed++
+	if lease.fail {
+		return errors.New("lease lost")
+	}
+
+	return nil
+}
+
+func (lease testLease) Release() error {
+	return nil
+}
+
+func TestLockerRenew(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+
+	var lock sync.Mutex
+	renewed := 0
+	cancelled := false
+
+	manager := period_job.NewManager(ctx, container.New())
+	manager.SetLocker(period_job.LockerFunc(func(ctx context.Context, name string, tick time.Time, ttl time.Duration) (period_job.Lease, error) {
+		return testLease{lock: &lock, renewed: &renewed, fail: name == "lost"}, nil
+	}), 30*time.Millisecond)
+
+	manager.Run("renew", func(ctx context.Context) {
+		select {
+		case <-time.After(100 * time.Millisecond):
+		case <-ctx.Done():
+		}
+	}, time.Hour, period_job.WithImmediately())
+
+	manager.Run("lost", func(ctx context.Context) {
+		select {
+		case <-time.After(200 * time.Millisecond):
+		case <-ctx.Done():
+			lock.Lock()
+			cancelled = true
+			lock.Unlock()
+		}
+	}, time.Hour, period_job.WithImmediately())
+
+	manager.Wait()
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	if renewed < 4 {
+		t.Errorf("test failed, expect lease renewed at least 4 times, got %d", renewed)
+	}
+
+	if !cancelled {
+		t.Error("test failed, job should be cancelled after lease lost")
+	}
+}
+
+func TestFileLockerTriggerNow(t *testing.T) {
+	dir, err := ioutil.TempDir("", "period_job_lock")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	locker, err := period_job.NewFileLocker(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+
+	manager := period_job.NewManager(ctx, container.New())
+	manager.SetLocker(locker, time.Second)
+
+	var lock sync.Mutex
+	runs := 0
+	block := make(chan struct{})
+	_ = manager.Run("Triggered", func() {
+		lock.Lock()
+		runs++
+		lock.Unlock()
+		<-block
+	}, time.Hour, period_job.WithImmediately())
+
+	time.Sleep(50 * time.Millisecond)
+
+	// 任务仍在执行，根据重叠策略跳过，需要告知调用方
+	if err := manager.TriggerNow("Triggered"); !errors.Is(err, period_job.ErrSkipped) {
+		t.Errorf("test failed, expect ErrSkipped, got %v", err)
+	}
+	close(block)
+	time.Sleep(50 * time.Millisecond)
+
+	// 手动触发不受每个调度周期只执行一次的限制
+	for i := 0; i < 2; i++ {
+		if err := manager.TriggerNow("Triggered"); err != nil {
+			t.Errorf("test failed, trigger: %s", err)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	manager.Wait()
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	if runs != 3 {
+		t.Errorf("test failed, expect 3 runs, got %d", runs)
+	}
+}
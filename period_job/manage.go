@@ -0,0 +1,121 @@
+package period_job
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/mylxsw/asteria/log"
+)
+
+// currentSchedule 返回任务当前的调度计划
+func (runner *jobRunner) currentSchedule() Schedule {
+	runner.lock.Lock()
+	defer runner.lock.Unlock()
+
+	return runner.schedule
+}
+
+// getJob 根据名称查询任务
+func (jm *Manager) getJob(name string) (*jobRunner, error) {
+	jm.lock.RLock()
+	defer jm.lock.RUnlock()
+
+	runner, ok := jm.jobs[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrJobNotFound, name)
+	}
+
+	return runner, nil
+}
+
+// Jobs 返回所有已注册的任务名称，按照名称排序
+func (jm *Manager) Jobs() []string {
+	jm.lock.RLock()
+	defer jm.lock.RUnlock()
+
+	names := make([]string, 0, len(jm.jobs))
+	for name := range jm.jobs {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	return names
+}
+
+// Remove 移除任务，停止后续调度，正在执行中的任务上下文会被取消
+func (jm *Manager) Remove(name string) error {
+	jm.lock.Lock()
+	runner, ok := jm.jobs[name]
+	if !ok {
+		jm.lock.Unlock()
+		return fmt.Errorf("%w: %s", ErrJobNotFound, name)
+	}
+
+	delete(jm.jobs, name)
+	delete(jm.pauseJobs, name)
+	jm.lock.Unlock()
+
+	runner.cancel()
+	log.Debugf("Job %s removed", name)
+
+	return nil
+}
+
+// Reschedule 修改任务的执行间隔，下一次执行时间从当前时间开始重新计算，interval 必须大于 0
+func (jm *Manager) Reschedule(name string, interval time.Duration) error {
+	if interval <= 0 {
+		return fmt.Errorf("invalid interval %s for job %s: interval must be greater than 0", interval, name)
+	}
+
+	return jm.RescheduleSchedule(name, Every(interval))
+}
+
+// RescheduleCron 修改任务的 cron 表达式
+func (jm *Manager) RescheduleCron(name string, spec string) error {
+	schedule, err := Cron(spec)
+	if err != nil {
+		return err
+	}
+
+	return jm.RescheduleSchedule(name, schedule)
+}
+
+// RescheduleSchedule 修改任务的调度计划
+func (jm *Manager) RescheduleSchedule(name string, schedule Schedule) error {
+	runner, err := jm.getJob(name)
+	if err != nil {
+		return err
+	}
+
+	runner.lock.Lock()
+	runner.schedule = schedule
+	runner.lock.Unlock()
+
+	select {
+	case runner.changed <- struct{}{}:
+	default:
+	}
+
+	log.Debugf("Job %s rescheduled", name)
+	return nil
+}
+
+// TriggerNow 立即触发一次任务执行（不影响后续调度），即使任务已经暂停，仍然遵循任务的重叠策略，
+// 根据重叠策略跳过时返回 ErrSkipped。手动触发的执行不获取分布式锁，不受每个调度周期只执行一次的限制
+func (jm *Manager) TriggerNow(name string) error {
+	runner, err := jm.getJob(name)
+	if err != nil {
+		return err
+	}
+
+	if err := runner.ctx.Err(); err != nil {
+		return fmt.Errorf("job %s has been stopped: %w", name, err)
+	}
+
+	if !jm.dispatch(runner, time.Now(), true) {
+		return fmt.Errorf("trigger job %s: %w", name, ErrSkipped)
+	}
+
+	return nil
+}
@@ -0,0 +1,88 @@
+package period_job_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mylxsw/container"
+	"github.com/mylxsw/go-toolkit/period_job"
+)
+
+func TestDynamicManagement(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+
+	manager := period_job.NewManager(ctx, container.New())
+
+	removed, rescheduled, triggered := &DemoJob{}, &DemoJob{}, &DemoJob{}
+	if err := manager.Run("removed", removed, 10*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	_ = manager.Run("rescheduled", rescheduled, time.Hour)
+	_ = manager.Run("triggered", triggered, time.Hour)
+
+	if err := manager.Run("removed", &DemoJob{}, time.Second); !errors.Is(err, period_job.ErrJobExists) {
+		t.Errorf("test failed, expect ErrJobExists, got %v", err)
+	}
+
+	if err := manager.Run("invalid", struct{}{}, time.Second); err == nil {
+		t.Error("test failed, expect error for job without Handle method")
+	}
+
+	if jobs := manager.Jobs(); len(jobs) != 3 || jobs[0] != "removed" || jobs[2] != "triggered" {
+		t.Errorf("test failed, got %v", jobs)
+	}
+
+	go func() {
+		<-time.After(55 * time.Millisecond)
+		if err := manager.Remove("removed"); err != nil {
+			t.Errorf("remove failed: %s", err)
+		}
+
+		if err := manager.Reschedule("rescheduled", 20*time.Millisecond); err != nil {
+			t.Errorf("reschedule failed: %s", err)
+		}
+
+		manager.Pause("triggered", true)
+		for i := 0; i < 2; i++ {
+			if err := manager.TriggerNow("triggered"); err != nil {
+				t.Errorf("trigger failed: %s", err)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}()
+
+	manager.Wait()
+
+	if count := removed.Count(); count < 3 || count > 6 {
+		t.Errorf("test failed, removed job should stop after removal, got %d runs", count)
+	}
+
+	if rescheduled.Count() < 5 {
+		t.Errorf("test failed, rescheduled job should run every 20ms, got %d runs", rescheduled.Count())
+	}
+
+	if triggered.Count() != 2 {
+		t.Errorf("test failed, expect triggered 2 times, got %d", triggered.Count())
+	}
+
+	if _, ok := manager.JobStatus("removed"); ok {
+		t.Error("test failed, removed job should not have status")
+	}
+
+	for _, err := range []error{manager.Remove("removed"), manager.Reschedule("not-exist", time.Second), manager.TriggerNow("not-exist")} {
+		if !errors.Is(err, period_job.ErrJobNotFound) {
+			t.Errorf("test failed, expect ErrJobNotFound, got %v", err)
+		}
+	}
+
+	if err := manager.Reschedule("rescheduled", 0); err == nil {
+		t.Error("test failed, expect error for non-positive interval")
+	}
+
+	if err := manager.Run("removed", removed, time.Second); err != nil {
+		t.Errorf("test failed, name should be reusable after removal: %s", err)
+	}
+}
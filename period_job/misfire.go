@@ -0,0 +1,101 @@
+package period_job
+
+import (
+	"time"
+
+	"github.com/mylxsw/asteria/log"
+)
+
+// SetStore 设置任务状态存储，设置后每次执行完毕都会记录执行时间，
+// 任务启动时根据 WithMisfire 指定的策略补执行停止期间错过的调度
+func (jm *Manager) SetStore(store Store) {
+	jm.lock.Lock()
+	defer jm.lock.Unlock()
+
+	jm.store = store
+}
+
+func (jm *Manager) getStore() Store {
+	jm.lock.RLock()
+	defer jm.lock.RUnlock()
+
+	return jm.store
+}
+
+// saveLastRun 记录任务最近一次执行对应的调度时间
+func (jm *Manager) saveLastRun(runner *jobRunner, tick time.Time) {
+	store := jm.getStore()
+	if store == nil {
+		return
+	}
+
+	if err := store.SetLastRun(runner.name, tick); err != nil {
+		log.Warningf("Job %s save last run failed: %s", runner.name, err)
+	}
+}
+
+// catchUp 根据任务的 misfire 策略，同步补执行停止期间错过的调度
+func (jm *Manager) catchUp(runner *jobRunner, schedule Schedule) {
+	store := jm.getStore()
+	if store == nil || runner.opts.misfire == MisfireSkip {
+		return
+	}
+
+	last, err := store.LastRun(runner.name)
+	if err != nil {
+		log.Warningf("Job %s load last run failed: %s", runner.name, err)
+		return
+	}
+
+	if last.IsZero() {
+		return
+	}
+
+	limit := 1
+	if runner.opts.misfire == MisfireRunAll {
+		limit = MaxMisfireRuns
+	}
+
+	// 只保留最近的 limit 次错过的调度
+	now := time.Now()
+	missed := make([]time.Time, 0, limit)
+	total := 0
+	for next := schedule.Next(last); !next.IsZero() && !next.After(now); next = schedule.Next(next) {
+		if len(missed) == limit {
+			missed = append(missed[1:], next)
+		} else {
+			missed = append(missed, next)
+		}
+
+		total++
+	}
+
+	if total == 0 {
+		return
+	}
+
+	log.Warningf("Job %s missed %d runs since %s, %d of them will be executed (policy: %s)", runner.name, total, last, len(missed), runner.opts.misfire)
+
+	for _, tick := range missed {
+		if runner.ctx.Err() != nil {
+			return
+		}
+
+		jm.runSync(runner, tick)
+	}
+}
+
+// runSync 同步执行一次任务，执行期间按照正在运行计数，保证重叠策略生效
+func (jm *Manager) runSync(runner *jobRunner, tick time.Time) {
+	runner.lock.Lock()
+	runner.running++
+	runner.lock.Unlock()
+
+	defer func() {
+		runner.lock.Lock()
+		runner.running--
+		runner.lock.Unlock()
+	}()
+
+	_ = jm.execute(runner, tick, false)
+}
@@ -0,0 +1,110 @@
+package period_job
+
+import (
+	"time"
+
+	"github.com/mylxsw/go-toolkit/failover/retry"
+)
+
+// OverlapPolicy 上一次执行尚未结束，又到了下一次执行时间时的处理策略
+type OverlapPolicy int
+
+const (
+	// OverlapSkip 上一次执行尚未结束时，跳过本次执行（默认策略）
+	OverlapSkip OverlapPolicy = iota
+	// OverlapQueue 上一次执行尚未结束时，等待其结束后再执行，最多只排队一次
+	OverlapQueue
+	// OverlapAllow 允许多次执行同时运行
+	OverlapAllow
+)
+
+// String 策略的字符串表示
+func (policy OverlapPolicy) String() string {
+	switch policy {
+	case OverlapSkip:
+		return "skip"
+	case OverlapQueue:
+		return "queue"
+	case OverlapAllow:
+		return "allow"
+	}
+
+	return ""
+}
+
+// MisfirePolicy 启动时，发现任务在停止期间错过了调度时的处理策略，需要配合 Manager.SetStore 使用
+type MisfirePolicy int
+
+const (
+	// MisfireSkip 忽略错过的调度（默认策略）
+	MisfireSkip MisfirePolicy = iota
+	// MisfireRunOnce 错过了调度时，启动后立即补执行一次
+	MisfireRunOnce
+	// MisfireRunAll 启动后依次补执行所有错过的调度，最多补执行 MaxMisfireRuns 次
+	MisfireRunAll
+)
+
+// MaxMisfireRuns MisfireRunAll 策略下最多补执行的次数
+const MaxMisfireRuns = 100
+
+// String 策略的字符串表示
+func (policy MisfirePolicy) String() string {
+	switch policy {
+	case MisfireSkip:
+		return "skip"
+	case MisfireRunOnce:
+		return "run-once"
+	case MisfireRunAll:
+		return "run-all"
+	}
+
+	return ""
+}
+
+// jobOptions 任务配置
+type jobOptions struct {
+	overlap       OverlapPolicy
+	timeout       time.Duration
+	immediately   bool
+	historySize   int
+	withoutLocker bool
+	misfire       MisfirePolicy
+
+	retries          int
+	retryOptions     []retry.Option
+	failureThreshold int
+	failureHandler   func(evt JobFailedEvent)
+	recoveryHandler  func(evt JobRecoveredEvent)
+}
+
+// JobOption 任务配置选项
+type JobOption func(opts *jobOptions)
+
+// WithOverlap 设置任务执行重叠时的处理策略
+func WithOverlap(policy OverlapPolicy) JobOption {
+	return func(opts *jobOptions) {
+		opts.overlap = policy
+	}
+}
+
+// WithTimeout 设置任务单次执行的最长时间，超时后传递给任务的 context 会被取消，
+// 并且本次执行被视为超时失败。任务本身需要响应 context 的取消才能真正结束，在此之前仍然视为正在执行（遵循重叠策略，并且持有分布式锁）
+func WithTimeout(timeout time.Duration) JobOption {
+	return func(opts *jobOptions) {
+		opts.timeout = timeout
+	}
+}
+
+// WithMisfire 设置任务错过调度时的处理策略
+func WithMisfire(policy MisfirePolicy) JobOption {
+	return func(opts *jobOptions) {
+		opts.misfire = policy
+	}
+}
+
+// WithImmediately 任务注册后立即执行一次，而不是等到第一次调度时间
+func WithImmediately() JobOption {
+	return func(opts *jobOptions) {
+		opts.immediately = true
+	}
+}
@@ -0,0 +1,40 @@
+package period_job
+
+import "time"
+
+// Schedule 任务调度计划
+type Schedule interface {
+	// Next 返回 t 之后的下一次执行时间，返回零值表示不再执行
+	Next(t time.Time) time.Time
+}
+
+// intervalSchedule 固定时间间隔的调度计划
+type intervalSchedule struct {
+	interval time.Duration
+}
+
+// Every 创建一个固定时间间隔的调度计划
+func Every(interval time.Duration) Schedule {
+	if interval <= 0 {
+		panic("interval must be greater than 0")
+	}
+
+	return intervalSchedule{interval: interval}
+}
+
+// Next 返回 t 之后的下一次执行时间
+func (s intervalSchedule) Next(t time.Time) time.Time {
+	return t.Add(s.interval)
+}
+
+// lockPeriod 返回 tick 所在的调度周期，作为分布式锁中本次调度的标识。
+// 固定间隔任务的调度时间（以及立即执行时的当前时间）取决于各个实例自身的时钟和启动时间，
+// 相互之间总会有细微差别，因此对齐到间隔的整数倍，保证同一个周期在所有实例中只执行一次；
+// cron 调度时间本身已经对齐，只去掉秒以下的部分
+func lockPeriod(schedule Schedule, tick time.Time) time.Time {
+	if s, ok := schedule.(intervalSchedule); ok {
+		return tick.Truncate(s.interval)
+	}
+
+	return tick.Truncate(time.Second)
+}
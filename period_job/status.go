@@ -0,0 +1,184 @@
+package period_job
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"time"
+)
+
+// RunRecord 任务单次执行记录
+type RunRecord struct {
+	Start    time.Time     `json:"start"`
+	End      time.Time     `json:"end"`
+	Duration time.Duration `json:"duration"`
+	Error    string        `json:"error,omitempty"`
+}
+
+// JobStatus 任务运行状态
+type JobStatus struct {
+	Name                string        `json:"name"`
+	LastStart           time.Time     `json:"last_start"`
+	LastEnd             time.Time     `json:"last_end"`
+	LastDuration        time.Duration `json:"last_duration"`
+	LastError           string        `json:"last_error,omitempty"`
+	ConsecutiveFailures int           `json:"consecutive_failures"`
+	RunCount            int           `json:"run_count"`
+	FailureCount        int           `json:"failure_count"`
+	Running             int           `json:"running"`
+	Paused              bool          `json:"paused"`
+	NextRun             time.Time     `json:"next_run"`
+	History             []RunRecord   `json:"history,omitempty"`
+}
+
+// jobStats 任务运行统计信息，由 jobRunner.lock 保护
+type jobStats struct {
+	lastStart           time.Time
+	lastEnd             time.Time
+	lastDuration        time.Duration
+	lastError           string
+	consecutiveFailures int
+	runCount            int
+	failureCount        int
+	nextRun             time.Time
+	history             []RunRecord
+}
+
+// WithHistory 保留任务最近 size 次的执行记录，可以通过 Manager.Status 查询
+func WithHistory(size int) JobOption {
+	return func(opts *jobOptions) {
+		opts.historySize = size
+	}
+}
+
+// recordStart 记录任务开始执行
+func (runner *jobRunner) recordStart(start time.Time) {
+	runner.lock.Lock()
+	defer runner.lock.Unlock()
+
+	runner.stats.lastStart = start
+}
+
+// recordEnd 记录任务执行结束
+func (runner *jobRunner) recordEnd(start time.Time, end time.Time, err error) {
+	runner.lock.Lock()
+	defer runner.lock.Unlock()
+
+	record := RunRecord{Start: start, End: end, Duration: end.Sub(start)}
+	if err != nil {
+		record.Error = err.Error()
+	}
+
+	stats := &runner.stats
+	stats.lastEnd = end
+	stats.lastDuration = record.Duration
+	stats.lastError = record.Error
+	stats.runCount++
+	if err != nil {
+		stats.failureCount++
+		stats.consecutiveFailures++
+	} else {
+		stats.consecutiveFailures = 0
+	}
+
+	if runner.opts.historySize > 0 {
+		stats.history = append(stats.history, record)
+		if len(stats.history) > runner.opts.historySize {
+			stats.history = stats.history[len(stats.history)-runner.opts.historySize:]
+		}
+	}
+}
+
+func (runner *jobRunner) setNextRun(next time.Time) {
+	runner.lock.Lock()
+	defer runner.lock.Unlock()
+
+	runner.stats.nextRun = next
+}
+
+func (runner *jobRunner) nextRun() time.Time {
+	runner.lock.Lock()
+	defer runner.lock.Unlock()
+
+	return runner.stats.nextRun
+}
+
+// status 返回任务运行状态
+func (runner *jobRunner) status(paused bool) JobStatus {
+	runner.lock.Lock()
+	defer runner.lock.Unlock()
+
+	stats := runner.stats
+	status := JobStatus{
+		Name:                runner.name,
+		LastStart:           stats.lastStart,
+		LastEnd:             stats.lastEnd,
+		LastDuration:        stats.lastDuration,
+		LastError:           stats.lastError,
+		ConsecutiveFailures: stats.consecutiveFailures,
+		RunCount:            stats.runCount,
+		FailureCount:        stats.failureCount,
+		Running:             runner.running,
+		Paused:              paused,
+		NextRun:             stats.nextRun,
+	}
+
+	if len(stats.history) > 0 {
+		status.History = make([]RunRecord, len(stats.history))
+		copy(status.History, stats.history)
+	}
+
+	return status
+}
+
+// Status 返回所有任务的运行状态，按照任务名称排序
+func (jm *Manager) Status() []JobStatus {
+	jm.lock.RLock()
+	runners := make([]*jobRunner, 0, len(jm.jobs))
+	for _, runner := range jm.jobs {
+		runners = append(runners, runner)
+	}
+	jm.lock.RUnlock()
+
+	statuses := make([]JobStatus, 0, len(runners))
+	for _, runner := range runners {
+		statuses = append(statuses, runner.status(jm.Paused(runner.name)))
+	}
+
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Name < statuses[j].Name })
+
+	return statuses
+}
+
+// JobStatus 返回指定任务的运行状态
+func (jm *Manager) JobStatus(name string) (JobStatus, bool) {
+	jm.lock.RLock()
+	runner, ok := jm.jobs[name]
+	jm.lock.RUnlock()
+
+	if !ok {
+		return JobStatus{}, false
+	}
+
+	return runner.status(jm.Paused(name)), true
+}
+
+// StatusHandler 返回以 JSON 格式输出任务运行状态的 HTTP Handler，
+// 请求参数中包含 name 时只返回该任务的状态，任务不存在时返回 404
+func (jm *Manager) StatusHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var res interface{} = jm.Status()
+		if name := r.URL.Query().Get("name"); name != "" {
+			status, ok := jm.JobStatus(name)
+			if !ok {
+				http.Error(w, "job not found", http.StatusNotFound)
+				return
+			}
+
+			res = status
+		}
+
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		_ = json.NewEncoder(w).Encode(res)
+	})
+}
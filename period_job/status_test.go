@@ -0,0 +1,88 @@
+package period_job_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/mylxsw/container"
+	"github.com/mylxsw/go-toolkit/period_job"
+)
+
+func TestJobStatus(t *testing.T) {
+	cc := container.New()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	manager := period_job.NewManager(ctx, cc)
+
+	manager.Run("failed", func() error {
+		return errors.New("test error")
+	}, 10*time.Millisecond, period_job.WithHistory(3))
+	manager.Run("succeed", &DemoJob{}, 10*time.Millisecond)
+	manager.Pause("succeed", true)
+
+	time.Sleep(50 * time.Millisecond)
+
+	next, ok := manager.NextRun("failed")
+	if !ok || next.Before(time.Now().Add(-10*time.Millisecond)) {
+		t.Errorf("test failed, invalid next run: %s", next)
+	}
+
+	manager.Wait()
+
+	statuses := manager.Status()
+	if len(statuses) != 2 || statuses[0].Name != "failed" || statuses[1].Name != "succeed" {
+		t.Fatalf("test failed, got %+v", statuses)
+	}
+
+	failed := statuses[0]
+	if failed.RunCount < 3 || failed.FailureCount != failed.RunCount || failed.ConsecutiveFailures != failed.RunCount {
+		t.Errorf("test failed, invalid counters: %+v", failed)
+	}
+
+	if failed.LastError != "test error" || failed.LastStart.IsZero() || failed.LastEnd.Before(failed.LastStart) {
+		t.Errorf("test failed, invalid last run: %+v", failed)
+	}
+
+	if len(failed.History) != 3 || failed.History[2].Error != "test error" {
+		t.Errorf("test failed, expect 3 history records, got %+v", failed.History)
+	}
+
+	if succeed := statuses[1]; !succeed.Paused || succeed.RunCount != 0 || len(succeed.History) != 0 {
+		t.Errorf("test failed, invalid paused job status: %+v", succeed)
+	}
+
+	server := httptest.NewServer(manager.StatusHandler())
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "?name=failed")
+	if err != nil {
+		t.Fatalf("request failed: %s", err)
+	}
+	defer resp.Body.Close()
+
+	var status period_job.JobStatus
+	if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
+		t.Fatalf("decode failed: %s", err)
+	}
+
+	if status.Name != "failed" || status.RunCount != failed.RunCount {
+		t.Errorf("test failed, got %+v", status)
+	}
+
+	resp2, err := http.Get(server.URL + "?name=not-exist")
+	if err != nil {
+		t.Fatalf("request failed: %s", err)
+	}
+	resp2.Body.Close()
+
+	if resp2.StatusCode != http.StatusNotFound {
+		t.Errorf("test failed, expect 404, got %d", resp2.StatusCode)
+	}
+}
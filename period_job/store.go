@@ -0,0 +1,89 @@
+package period_job
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Store 任务状态存储，用于在重启后恢复任务的最近一次执行时间
+type Store interface {
+	// LastRun 返回任务最近一次执行对应的调度时间，没有记录时返回零值
+	LastRun(name string) (time.Time, error)
+	// SetLastRun 记录任务最近一次执行对应的调度时间
+	SetLastRun(name string, tick time.Time) error
+}
+
+// FileStore 基于本地 JSON 文件的 Store 实现
+type FileStore struct {
+	path     string
+	lock     sync.Mutex
+	lastRuns map[string]time.Time
+}
+
+// NewFileStore 创建一个基于本地文件的 Store，文件已经存在时加载其中的记录
+func NewFileStore(path string) (*FileStore, error) {
+	store := &FileStore{
+		path:     path,
+		lastRuns: make(map[string]time.Time),
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return store, nil
+		}
+
+		return nil, err
+	}
+
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &store.lastRuns); err != nil {
+			return nil, err
+		}
+	}
+
+	return store, nil
+}
+
+// LastRun 返回任务最近一次执行对应的调度时间
+func (store *FileStore) LastRun(name string) (time.Time, error) {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+
+	return store.lastRuns[name], nil
+}
+
+// SetLastRun 记录任务最近一次执行对应的调度时间，先写入临时文件再重命名，避免写入过程中崩溃导致文件损坏
+func (store *FileStore) SetLastRun(name string, tick time.Time) error {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+
+	store.lastRuns[name] = tick
+
+	data, err := json.Marshal(store.lastRuns)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(store.path), filepath.Base(store.path)+".tmp")
+	if err != nil {
+		return err
+	}
+
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+
+	return os.Rename(tmp.Name(), store.path)
+}
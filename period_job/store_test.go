@@ -0,0 +1,82 @@
+package period_job_test
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mylxsw/container"
+	"github.com/mylxsw/go-toolkit/period_job"
+)
+
+func TestMisfirePolicy(t *testing.T) {
+	dir, err := ioutil.TempDir("", "period_job_store")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "jobs.json")
+	store, err := period_job.NewFileStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 模拟停止前最后一次执行在 1 小时前，每 10 分钟执行一次，错过 6 次
+	lastRun := time.Now().Add(-time.Hour).Add(-time.Second)
+	for _, name := range []string{"skip", "once", "all"} {
+		if err := store.SetLastRun(name, lastRun); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// 重新加载，确保记录已经持久化
+	store, err = period_job.NewFileStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	manager := period_job.NewManager(ctx, container.New())
+	manager.SetStore(store)
+
+	var lock sync.Mutex
+	ticks := make(map[string][]time.Time)
+	for name, policy := range map[string]period_job.MisfirePolicy{
+		"skip": period_job.MisfireSkip,
+		"once": period_job.MisfireRunOnce,
+		"all":  period_job.MisfireRunAll,
+	} {
+		name := name
+		_ = manager.Run(name, func() {
+			lock.Lock()
+			defer lock.Unlock()
+
+			ticks[name] = append(ticks[name], time.Now())
+		}, 10*time.Minute, period_job.WithMisfire(policy))
+	}
+
+	manager.Wait()
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	if len(ticks["skip"]) != 0 || len(ticks["once"]) != 1 || len(ticks["all"]) != 6 {
+		t.Errorf("test failed, got skip=%d, once=%d, all=%d", len(ticks["skip"]), len(ticks["once"]), len(ticks["all"]))
+	}
+
+	last, _ := store.LastRun("all")
+	if expect := lastRun.Add(time.Hour); !last.Equal(expect) {
+		t.Errorf("test failed, expect last run %s, got %s", expect, last)
+	}
+
+	if last, _ := store.LastRun("skip"); !last.Equal(lastRun) {
+		t.Errorf("test failed, skipped job should keep last run %s, got %s", lastRun, last)
+	}
+}
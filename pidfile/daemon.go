@@ -0,0 +1,270 @@
+package pidfile
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+const (
+	// DefaultStartTimeout 后台进程启动后，等待其创建 pid 文件的默认时间
+	DefaultStartTimeout = 5 * time.Second
+	// DefaultStopTimeout 发送 SIGTERM 后，等待进程退出的默认时间，超时后发送 SIGKILL
+	DefaultStopTimeout = 10 * time.Second
+)
+
+// killTimeout 发送 SIGKILL 后等待进程退出的时间
+const killTimeout = 5 * time.Second
+
+// checkInterval 等待进程启动或者退出时的检查间隔
+const checkInterval = 50 * time.Millisecond
+
+// Daemon 基于 pid 文件的守护进程辅助工具，用于编写服务控制命令：
+//
+//	daemon := pidfile.NewDaemon("/var/run/app.pid", pidfile.WithArgs("run"), pidfile.WithLogFile("/var/log/app.log"))
+//	switch os.Args[1] {
+//	case "start":
+//		pid, err := daemon.Start()   // 在后台执行 app run
+//	case "run":
+//		err := daemon.Run(context.Background(), serve)
+//	case "stop":
+//		err := daemon.Stop()
+//	case "reload":
+//		err := daemon.Reload()
+//	case "status":
+//		pid, err := daemon.Status()
+//	}
+//
+// Start 以 Args 为参数重新执行当前程序，新进程在新的会话中运行，标准输入重定向到 /dev/null，标准输出和标准错误输出重定向到日志文件；
+// 后台进程调用 Run 创建 pid 文件并执行服务，收到 SIGTERM 或者 SIGINT 后结束 Run 的 ctx，收到 SIGHUP 后调用 reload 回调，
+// 服务返回后删除 pid 文件。Stop、Reload、Status 通过 pid 文件中记录的 pid 向后台进程发送信号或者查询状态
+type Daemon struct {
+	path          string
+	args          []string
+	logFile       string
+	startTimeout  time.Duration
+	stopTimeout   time.Duration
+	reloadHandler func()
+}
+
+// DaemonOption 守护进程配置选项
+type DaemonOption func(daemon *Daemon)
+
+// WithArgs 设置 Start 启动后台进程时使用的命令行参数，必须设置，并且不能是执行 Start 的命令本身（例如 start），
+// 否则后台进程会再次执行 Start，不断地启动新的进程
+func WithArgs(args ...string) DaemonOption {
+	return func(daemon *Daemon) {
+		daemon.args = args
+	}
+}
+
+// WithLogFile 设置后台进程标准输出和标准错误输出的日志文件，为空时重定向到 /dev/null
+func WithLogFile(path string) DaemonOption {
+	return func(daemon *Daemon) {
+		daemon.logFile = path
+	}
+}
+
+// WithStartTimeout 设置 Start 等待后台进程创建 pid 文件的时间
+func WithStartTimeout(timeout time.Duration) DaemonOption {
+	return func(daemon *Daemon) {
+		daemon.startTimeout = timeout
+	}
+}
+
+// WithStopTimeout 设置 Stop 等待进程退出的时间，超时后发送 SIGKILL
+func WithStopTimeout(timeout time.Duration) DaemonOption {
+	return func(daemon *Daemon) {
+		daemon.stopTimeout = timeout
+	}
+}
+
+// WithReloadHandler 设置 Run 收到 SIGHUP 信号时执行的函数
+func WithReloadHandler(handler func()) DaemonOption {
+	return func(daemon *Daemon) {
+		daemon.reloadHandler = handler
+	}
+}
+
+// NewDaemon 创建一个使用 path 作为 pid 文件的守护进程辅助工具
+func NewDaemon(path string, opts ...DaemonOption) *Daemon {
+	daemon := &Daemon{
+		path:         path,
+		startTimeout: DefaultStartTimeout,
+		stopTimeout:  DefaultStopTimeout,
+	}
+
+	for _, opt := range opts {
+		opt(daemon)
+	}
+
+	return daemon
+}
+
+// Start 在后台启动当前程序，等待后台进程创建 pid 文件后返回其 pid，
+// 已经有进程在运行时返回 ErrRunning，没有通过 WithArgs 设置后台进程的参数、后台进程在创建 pid 文件之前退出时返回错误
+func (daemon *Daemon) Start() (int, error) {
+	if len(daemon.args) == 0 {
+		return 0, errors.New("daemon args are required, set them with WithArgs")
+	}
+
+	if pid, err := daemon.Status(); err == nil {
+		return 0, fmt.Errorf("%w: pid %d, pid file %s", ErrRunning, pid, daemon.path)
+	}
+
+	executable, err := os.Executable()
+	if err != nil {
+		return 0, err
+	}
+
+	stdin, err := os.Open(os.DevNull)
+	if err != nil {
+		return 0, err
+	}
+	defer stdin.Close()
+
+	output := os.DevNull
+	flags := os.O_WRONLY
+	if daemon.logFile != "" {
+		output, flags = daemon.logFile, os.O_WRONLY|os.O_CREATE|os.O_APPEND
+	}
+
+	stdout, err := os.OpenFile(output, flags, 0644)
+	if err != nil {
+		return 0, err
+	}
+	defer stdout.Close()
+
+	cmd := exec.Command(executable, daemon.args...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = stdin, stdout, stdout
+	cmd.SysProcAttr = daemonSysProcAttr()
+
+	if err := cmd.Start(); err != nil {
+		return 0, err
+	}
+
+	exited := make(chan error, 1)
+	go func() {
+		exited <- cmd.Wait()
+	}()
+
+	deadline := time.After(daemon.startTimeout)
+	for {
+		if pid, err := ReadRunningPID(daemon.path); err == nil && pid == cmd.Process.Pid {
+			return pid, nil
+		}
+
+		select {
+		case err := <-exited:
+			return 0, fmt.Errorf("daemon exited before pid file created: %v", err)
+		case <-deadline:
+			return 0, fmt.Errorf("daemon %d did not create pid file %s in %s", cmd.Process.Pid, daemon.path, daemon.startTimeout)
+		case <-time.After(checkInterval):
+		}
+	}
+}
+
+// Run 创建 pid 文件并执行 fn，已经有进程在运行时返回 ErrRunning。
+// 收到 SIGTERM 或者 SIGINT 信号后结束传递给 fn 的 ctx，收到 SIGHUP 信号后执行 WithReloadHandler 设置的函数，
+// fn 返回后删除 pid 文件，返回 fn 的返回值
+func (daemon *Daemon) Run(ctx context.Context, fn func(ctx context.Context) error) error {
+	// 在创建 pid 文件之前注册信号处理，避免其它进程读取到 pid 后立即发送的信号使用默认的处理方式
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGTERM, syscall.SIGINT, syscall.SIGHUP)
+	defer signal.Stop(signals)
+
+	file, err := New(daemon.path)
+	if err != nil {
+		return err
+	}
+	defer file.Remove()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case sig := <-signals:
+				if sig == syscall.SIGHUP {
+					if daemon.reloadHandler != nil {
+						daemon.reloadHandler()
+					}
+					continue
+				}
+
+				cancel()
+				return
+			}
+		}
+	}()
+
+	return fn(ctx)
+}
+
+// Status 返回正在运行的进程的 pid，没有进程在运行（或者 pid 文件已经过期）时返回 ErrNotRunning
+func (daemon *Daemon) Status() (int, error) {
+	return ReadRunningPID(daemon.path)
+}
+
+// Reload 向正在运行的进程发送 SIGHUP 信号，没有进程在运行时返回 ErrNotRunning
+func (daemon *Daemon) Reload() error {
+	pid, err := daemon.Status()
+	if err != nil {
+		return err
+	}
+
+	return signalProcess(pid, syscall.SIGHUP)
+}
+
+// Stop 向正在运行的进程发送 SIGTERM 信号并等待其退出，超时后发送 SIGKILL 信号，
+// 没有进程在运行时返回 ErrNotRunning，进程被强制结束后删除遗留的 pid 文件和锁文件
+func (daemon *Daemon) Stop() error {
+	pid, err := daemon.Status()
+	if err != nil {
+		return err
+	}
+
+	if err := signalProcess(pid, syscall.SIGTERM); err != nil {
+		return err
+	}
+
+	if daemon.waitExit(pid, daemon.stopTimeout) {
+		return nil
+	}
+
+	if err := signalProcess(pid, syscall.SIGKILL); err != nil {
+		return err
+	}
+
+	if !daemon.waitExit(pid, killTimeout) {
+		return fmt.Errorf("process %d is still alive after killed", pid)
+	}
+
+	_ = os.Remove(daemon.path)
+	_ = os.Remove(lockPath(daemon.path))
+	return nil
+}
+
+// waitExit 等待 pid 文件中记录的进程退出，超时返回 false
+func (daemon *Daemon) waitExit(pid int, timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	for {
+		if current, err := daemon.Status(); err != nil || current != pid {
+			return true
+		}
+
+		if time.Now().After(deadline) {
+			return false
+		}
+
+		time.Sleep(checkInterval)
+	}
+}
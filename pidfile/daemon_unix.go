@@ -0,0 +1,18 @@
+//go:build !windows
+// +build !windows
+
+package pidfile
+
+import (
+	"syscall"
+)
+
+// daemonSysProcAttr 后台进程在新的会话中运行，脱离当前终端
+func daemonSysProcAttr() *syscall.SysProcAttr {
+	return &syscall.SysProcAttr{Setsid: true}
+}
+
+// signalProcess 向进程发送信号
+func signalProcess(pid int, sig syscall.Signal) error {
+	return syscall.Kill(pid, sig)
+}
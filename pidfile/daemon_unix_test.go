@@ -0,0 +1,147 @@
+//go:build !windows
+// +build !windows
+
+package pidfile_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mylxsw/go-toolkit/pidfile"
+)
+
+func TestDaemon(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	path, logFile := filepath.Join(dir, "app.pid"), filepath.Join(dir, "app.log")
+
+	os.Setenv("PIDFILE_DAEMON_HELPER", path)
+	defer os.Unsetenv("PIDFILE_DAEMON_HELPER")
+
+	daemon := pidfile.NewDaemon(path, pidfile.WithArgs("-test.run=TestDaemonHelper"), pidfile.WithLogFile(logFile), pidfile.WithStopTimeout(3*time.Second))
+
+	if _, err := daemon.Status(); !errors.Is(err, pidfile.ErrNotRunning) {
+		t.Errorf("expect ErrNotRunning before started, got %v", err)
+	}
+	if err := daemon.Stop(); !errors.Is(err, pidfile.ErrNotRunning) {
+		t.Errorf("expect ErrNotRunning when stopping a stopped daemon, got %v", err)
+	}
+
+	pid, err := daemon.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if current, err := daemon.Status(); err != nil || current != pid {
+		t.Errorf("expect daemon %d running, got %d, %v", pid, current, err)
+	}
+
+	if _, err := daemon.Start(); !errors.Is(err, pidfile.ErrRunning) {
+		t.Errorf("expect ErrRunning when starting twice, got %v", err)
+	}
+
+	if err := daemon.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	waitLog(t, logFile, "reloaded")
+
+	if err := daemon.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	waitLog(t, logFile, "stopped")
+
+	if _, err := daemon.Status(); !errors.Is(err, pidfile.ErrNotRunning) {
+		t.Errorf("expect ErrNotRunning after stopped, got %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("expect pid file removed after stopped, got %v", err)
+	}
+}
+
+func TestDaemonStartWithoutArgs(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	// 没有设置后台进程的参数时不能启动，避免以当前参数（例如 start）再次执行 Start
+	daemon := pidfile.NewDaemon(filepath.Join(dir, "app.pid"))
+	if _, err := daemon.Start(); err == nil {
+		t.Error("expect error when starting without args")
+	}
+}
+
+func TestDaemonKill(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	path, logFile := filepath.Join(dir, "app.pid"), filepath.Join(dir, "app.log")
+
+	os.Setenv("PIDFILE_DAEMON_HELPER", path)
+	os.Setenv("PIDFILE_DAEMON_HELPER_HANG", "1")
+	defer os.Unsetenv("PIDFILE_DAEMON_HELPER")
+	defer os.Unsetenv("PIDFILE_DAEMON_HELPER_HANG")
+
+	daemon := pidfile.NewDaemon(path, pidfile.WithArgs("-test.run=TestDaemonHelper"), pidfile.WithLogFile(logFile), pidfile.WithStopTimeout(200*time.Millisecond))
+	if _, err := daemon.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	// 后台进程不响应 SIGTERM，超时后被强制结束，遗留的 pid 文件和锁文件需要删除
+	if err := daemon.Stop(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, file := range []string{path, path + ".lock"} {
+		if _, err := os.Stat(file); !os.IsNotExist(err) {
+			t.Errorf("expect %s removed after killed, got %v", file, err)
+		}
+	}
+}
+
+// waitLog 等待日志文件中出现指定的内容
+func waitLog(t *testing.T, path string, expect string) {
+	t.Helper()
+
+	deadline := time.Now().Add(3 * time.Second)
+	for time.Now().Before(deadline) {
+		if data, _ := ioutil.ReadFile(path); strings.Contains(string(data), expect) {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	data, _ := ioutil.ReadFile(path)
+	t.Fatalf("timeout waiting for %q in log, got %q", expect, data)
+}
+
+// TestDaemonHelper 作为 TestDaemon 启动的后台进程运行
+func TestDaemonHelper(t *testing.T) {
+	path := os.Getenv("PIDFILE_DAEMON_HELPER")
+	if path == "" {
+		t.Skip("helper process")
+	}
+
+	daemon := pidfile.NewDaemon(path, pidfile.WithReloadHandler(func() {
+		fmt.Println("reloaded")
+	}))
+
+	err := daemon.Run(context.Background(), func(ctx context.Context) error {
+		fmt.Println("started")
+		<-ctx.Done()
+		if os.Getenv("PIDFILE_DAEMON_HELPER_HANG") != "" {
+			select {}
+		}
+		fmt.Println("stopped")
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,29 @@
+//go:build windows
+// +build windows
+
+package pidfile
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// daemonSysProcAttr 后台进程在新的进程组中运行
+func daemonSysProcAttr() *syscall.SysProcAttr {
+	return &syscall.SysProcAttr{CreationFlags: syscall.CREATE_NEW_PROCESS_GROUP}
+}
+
+// signalProcess windows 不支持信号，SIGTERM 和 SIGKILL 直接结束进程
+func signalProcess(pid int, sig syscall.Signal) error {
+	if sig != syscall.SIGTERM && sig != syscall.SIGKILL {
+		return fmt.Errorf("signal %s is not supported on windows", sig)
+	}
+
+	proc, err := os.FindProcess(pid)
+	if err != nil {
+		return err
+	}
+
+	return proc.Kill()
+}
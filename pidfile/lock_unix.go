@@ -0,0 +1,27 @@
+//go:build !windows
+// +build !windows
+
+package pidfile
+
+import (
+	"os"
+	"syscall"
+)
+
+// flock 以非阻塞的方式对文件加排它锁，已经被锁定时返回 errLocked
+func flock(f *os.File) error {
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		if err == syscall.EWOULDBLOCK {
+			return errLocked
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+// funlock 释放文件锁
+func funlock(f *os.File) error {
+	return syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+}
@@ -0,0 +1,18 @@
+//go:build windows
+// +build windows
+
+package pidfile
+
+import (
+	"os"
+)
+
+// flock windows 暂不支持文件锁
+func flock(f *os.File) error {
+	return errUnsupported
+}
+
+// funlock 释放文件锁
+func funlock(f *os.File) error {
+	return nil
+}
@@ -1,67 +1,224 @@
 // Package pidfile provides structure and helper functions to create and remove
 // PID file. A PID file is usually a file used to store the process ID of a
 // running process.
+//
+// New 创建的 pid 文件会同时创建一个 <path>.lock 锁文件，并在进程的整个生命周期内持有该文件的排它锁（flock），
+// 进程退出后锁会被自动释放，因此同时启动的多个实例中只有一个能够成功创建 pid 文件。
+// pid 文件通过写入临时文件后重命名的方式原子地更新，读取时忽略首尾的空白字符。
 package pidfile
 
 import (
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
+	"time"
 )
 
+var (
+	// ErrRunning pid 文件中记录的进程仍在运行
+	ErrRunning = errors.New("process is already running")
+	// ErrNotRunning pid 文件不存在，或者其中记录的进程已经退出
+	ErrNotRunning = errors.New("process is not running")
+
+	// errLocked 锁文件已经被其它进程锁定
+	errLocked = errors.New("file is locked")
+	// errUnsupported 当前平台不支持该操作
+	errUnsupported = errors.New("not supported on this platform")
+)
+
+// startTimeTolerance 比较进程启动时间与 pid 文件修改时间时允许的误差，/proc 中的系统启动时间精确到秒
+const startTimeTolerance = 2 * time.Second
+
 // PIDFile is a file used to store the process ID of a running process.
 type PIDFile struct {
 	path string
+	lock *os.File
 }
 
-func checkPIDFileAlreadyExists(path string) error {
-	if pidByte, err := ioutil.ReadFile(path); err == nil {
-		pidString := strings.TrimSpace(string(pidByte))
-		if pid, err := strconv.Atoi(pidString); err == nil {
-			if processExists(pid) {
-				return fmt.Errorf("pid [%s] file found: %s", string(pidByte), path)
-			}
+// New creates a PIDfile using the specified path.
+//
+// 其它进程持有锁文件（或者在不支持文件锁的平台上，pid 文件中记录的进程仍在运行）时返回 ErrRunning
+func New(path string) (*PIDFile, error) {
+	lock, err := acquireLock(lockPath(path))
+	if err != nil && err != errUnsupported {
+		if err == errLocked {
+			pid, _ := ReadPIDFile(path)
+			return nil, fmt.Errorf("%w: pid %d, pid file %s", ErrRunning, pid, path)
 		}
 
+		return nil, err
 	}
-	return nil
-}
 
-// New creates a PIDfile using the specified path.
-func New(path string) (*PIDFile, error) {
-	if err := checkPIDFileAlreadyExists(path); err != nil {
-		return nil, err
+	// 不支持文件锁时，只能根据 pid 文件中记录的进程是否仍在运行来判断
+	if lock == nil {
+		if pid, err := ReadRunningPID(path); err == nil && pid != os.Getpid() {
+			return nil, fmt.Errorf("%w: pid %d, pid file %s", ErrRunning, pid, path)
+		}
 	}
-	if err := ioutil.WriteFile(path, []byte(fmt.Sprintf("%d", os.Getpid())), 0644); err != nil {
+
+	if err := Write(path, os.Getpid()); err != nil {
+		if lock != nil {
+			releaseLock(lock, true)
+		}
 		return nil, err
 	}
 
-	return &PIDFile{path: path}, nil
+	return &PIDFile{path: path, lock: lock}, nil
 }
 
-// Remove removes the PIDFile.
-func (file PIDFile) Remove() error {
-	if err := os.Remove(file.path); err != nil {
+// Path 返回 pid 文件路径
+func (file PIDFile) Path() string {
+	return file.path
+}
+
+// Write 将指定的 pid 原子地写入 pid 文件（写入临时文件后重命名），用于记录其它进程（例如子进程）的 pid
+func Write(path string, pid int) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+
+	if _, err := tmp.WriteString(strconv.Itoa(pid) + "\n"); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+
+	for _, fn := range []func() error{tmp.Sync, func() error { return tmp.Chmod(0644) }, tmp.Close} {
+		if err := fn(); err != nil {
+			_ = tmp.Close()
+			_ = os.Remove(tmp.Name())
+			return err
+		}
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		_ = os.Remove(tmp.Name())
 		return err
 	}
+
 	return nil
 }
 
-// ReadPIDFile 读取pid文件
+// Remove removes the PIDFile.
+//
+// 同时删除锁文件并释放锁
+func (file PIDFile) Remove() error {
+	err := os.Remove(file.path)
+	if file.lock != nil {
+		releaseLock(file.lock, true)
+	}
+
+	return err
+}
+
+// ReadPIDFile 读取pid文件，忽略首尾的空白字符
 func ReadPIDFile(pidfile string) (pid int, err error) {
-	f, err := os.Open(pidfile)
+	data, err := ioutil.ReadFile(pidfile)
 	if err != nil {
-		return
+		return 0, err
 	}
 
-	b, err := ioutil.ReadAll(f)
+	pid, err = strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil || pid <= 0 {
+		return 0, fmt.Errorf("invalid pid file %s: %q", pidfile, data)
+	}
+
+	return pid, nil
+}
+
+// ReadRunningPID 读取 pid 文件中记录的仍在运行的进程的 pid，pid 文件不存在或者已经过期时返回 ErrNotRunning。
+//
+// 以下情况视为 pid 文件已经过期：
+// 锁文件存在但是没有被锁定（创建 pid 文件的进程已经退出）；记录的进程已经不存在；
+// 进程的启动时间晚于 pid 文件的修改时间（pid 已经被其它进程复用，需要从 /proc 中获取进程启动时间）
+func ReadRunningPID(path string) (int, error) {
+	pid, err := ReadPIDFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, fmt.Errorf("%w: pid file %s not found", ErrNotRunning, path)
+		}
+		return 0, err
+	}
+
+	if locked, err := isLocked(lockPath(path)); err == nil && !locked {
+		return 0, fmt.Errorf("%w: pid file %s is not locked", ErrNotRunning, path)
+	}
+
+	if !processExists(pid) {
+		return 0, fmt.Errorf("%w: pid %d not found", ErrNotRunning, pid)
+	}
+
+	stat, err := os.Stat(path)
+	if err != nil {
+		return 0, err
+	}
+
+	if startTime, err := processStartTime(pid); err == nil && startTime.After(stat.ModTime().Add(startTimeTolerance)) {
+		return 0, fmt.Errorf("%w: pid %d has been reused by another process", ErrNotRunning, pid)
+	}
+
+	return pid, nil
+}
+
+// lockPath pid 文件对应的锁文件
+func lockPath(path string) string {
+	return path + ".lock"
+}
+
+// acquireLock 创建并锁定锁文件，锁文件已经被锁定时返回 errLocked
+func acquireLock(path string) (*os.File, error) {
+	for {
+		f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := flock(f); err != nil {
+			_ = f.Close()
+			return nil, err
+		}
+
+		// 锁文件可能在加锁之前被上一个持有者删除，此时锁定的是已经被删除的文件，需要重新创建
+		current, err := os.Stat(path)
+		if opened, statErr := f.Stat(); err == nil && statErr == nil && os.SameFile(current, opened) {
+			return f, nil
+		}
+
+		_ = funlock(f)
+		_ = f.Close()
+	}
+}
+
+// releaseLock 释放锁，remove 为 true 时先删除锁文件
+func releaseLock(f *os.File, remove bool) {
+	if remove {
+		_ = os.Remove(f.Name())
+	}
+
+	_ = funlock(f)
+	_ = f.Close()
+}
+
+// isLocked 判断锁文件是否被锁定，锁文件不存在或者不支持文件锁时返回错误
+func isLocked(path string) (bool, error) {
+	f, err := os.OpenFile(path, os.O_RDWR, 0644)
 	if err != nil {
-		return
+		return false, err
+	}
+	defer f.Close()
+
+	if err := flock(f); err != nil {
+		if err == errLocked {
+			return true, nil
+		}
+		return false, err
 	}
 
-	pid64, err := strconv.ParseInt(string(b), 10, 64)
-	pid = int(pid64)
-	return
+	_ = funlock(f)
+	return false, nil
 }
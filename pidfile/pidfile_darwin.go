@@ -1,9 +1,11 @@
+//go:build darwin
 // +build darwin
 
 package pidfile
 
 import (
 	"syscall"
+	"time"
 )
 
 func processExists(pid int) bool {
@@ -16,3 +18,8 @@ func processExists(pid int) bool {
 
 	return true
 }
+
+// processStartTime OS X 没有 proc 文件系统，无法获取进程的启动时间
+func processStartTime(pid int) (time.Time, error) {
+	return time.Time{}, errUnsupported
+}
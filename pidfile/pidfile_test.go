@@ -0,0 +1,163 @@
+package pidfile_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mylxsw/go-toolkit/pidfile"
+)
+
+func tempDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "pidfile")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return dir
+}
+
+func TestPIDFile(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "app.pid")
+	file, err := pidfile.New(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if data, err := ioutil.ReadFile(path); err != nil || string(data) != strconv.Itoa(os.Getpid())+"\n" {
+		t.Errorf("unexpected pid file content %q, %v", data, err)
+	}
+
+	if pid, err := pidfile.ReadRunningPID(path); err != nil || pid != os.Getpid() {
+		t.Errorf("expect running pid %d, got %d, %v", os.Getpid(), pid, err)
+	}
+
+	if runtime.GOOS != "windows" {
+		if _, err := pidfile.New(path); !errors.Is(err, pidfile.ErrRunning) {
+			t.Errorf("expect ErrRunning while pid file is locked, got %v", err)
+		}
+	}
+
+	if err := file.Remove(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := pidfile.ReadRunningPID(path); !errors.Is(err, pidfile.ErrNotRunning) {
+		t.Errorf("expect ErrNotRunning after removed, got %v", err)
+	}
+
+	if files, _ := ioutil.ReadDir(dir); len(files) != 0 {
+		t.Errorf("expect pid file and lock file removed, got %d files", len(files))
+	}
+}
+
+func TestPIDFileConcurrent(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("file lock is not supported on windows")
+	}
+
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "app.pid")
+
+	var lock sync.Mutex
+	created := make([]*pidfile.PIDFile, 0)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			if file, err := pidfile.New(path); err == nil {
+				lock.Lock()
+				created = append(created, file)
+				lock.Unlock()
+			} else if !errors.Is(err, pidfile.ErrRunning) {
+				t.Errorf("unexpected error: %s", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(created) != 1 {
+		t.Fatalf("expect exactly one pid file created, got %d", len(created))
+	}
+
+	_ = created[0].Remove()
+}
+
+func TestReadPIDFile(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "app.pid")
+	for content, expect := range map[string]int{"123": 123, "123\n": 123, " 456 \r\n": 456, "abc": 0, "": 0, "-1": 0} {
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		pid, err := pidfile.ReadPIDFile(path)
+		if pid != expect || (expect == 0) != (err != nil) {
+			t.Errorf("read %q: expect %d, got %d, %v", content, expect, pid, err)
+		}
+	}
+}
+
+func TestReadRunningPIDStale(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "app.pid")
+	if err := pidfile.Write(path, os.Getpid()); err != nil {
+		t.Fatal(err)
+	}
+
+	if pid, err := pidfile.ReadRunningPID(path); err != nil || pid != os.Getpid() {
+		t.Errorf("expect running pid %d, got %d, %v", os.Getpid(), pid, err)
+	}
+
+	// pid 文件的修改时间早于进程的启动时间，说明 pid 已经被复用
+	if runtime.GOOS == "linux" {
+		old := time.Now().Add(-24 * time.Hour)
+		if err := os.Chtimes(path, old, old); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := pidfile.ReadRunningPID(path); !errors.Is(err, pidfile.ErrNotRunning) {
+			t.Errorf("expect ErrNotRunning for reused pid, got %v", err)
+		}
+
+		if err := pidfile.Write(path, os.Getpid()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// 锁文件存在但是没有被锁定，说明创建 pid 文件的进程已经退出
+	if runtime.GOOS != "windows" {
+		if err := ioutil.WriteFile(path+".lock", nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := pidfile.ReadRunningPID(path); !errors.Is(err, pidfile.ErrNotRunning) {
+			t.Errorf("expect ErrNotRunning for unlocked pid file, got %v", err)
+		}
+
+		// 上一个进程遗留的锁文件不影响创建新的 pid 文件
+		file, err := pidfile.New(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = file.Remove()
+	}
+}
@@ -1,16 +1,80 @@
+//go:build !windows && !darwin
 // +build !windows,!darwin
 
 package pidfile
 
 import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
 	"os"
 	"path/filepath"
 	"strconv"
+	"strings"
+	"time"
 )
 
+// clockTicks /proc/<pid>/stat 中时间的单位（USER_HZ），Linux 下固定为 100
+const clockTicks = 100
+
 func processExists(pid int) bool {
 	if _, err := os.Stat(filepath.Join("/proc", strconv.Itoa(pid))); err == nil {
 		return true
 	}
 	return false
 }
+
+// processStartTime 从 /proc 中获取进程的启动时间
+func processStartTime(pid int) (time.Time, error) {
+	stat, err := ioutil.ReadFile(filepath.Join("/proc", strconv.Itoa(pid), "stat"))
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	// 第二个字段为进程名称，可能包含空格和括号，从最后一个右括号之后开始解析，
+	// 右括号之后的第一个字段为第 3 个字段，starttime 为第 22 个字段（自系统启动以来的时钟周期数）
+	pos := bytes.LastIndexByte(stat, ')')
+	if pos < 0 {
+		return time.Time{}, fmt.Errorf("invalid stat for process %d", pid)
+	}
+
+	fields := strings.Fields(string(stat[pos+1:]))
+	if len(fields) < 20 {
+		return time.Time{}, fmt.Errorf("invalid stat for process %d", pid)
+	}
+
+	ticks, err := strconv.ParseInt(fields[19], 10, 64)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	bootTime, err := systemBootTime()
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return bootTime.Add(time.Duration(ticks) * time.Second / clockTicks), nil
+}
+
+// systemBootTime 从 /proc/stat 中获取系统的启动时间
+func systemBootTime() (time.Time, error) {
+	stat, err := ioutil.ReadFile("/proc/stat")
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	for _, line := range strings.Split(string(stat), "\n") {
+		if !strings.HasPrefix(line, "btime ") {
+			continue
+		}
+
+		sec, err := strconv.ParseInt(strings.TrimSpace(strings.TrimPrefix(line, "btime ")), 10, 64)
+		if err != nil {
+			return time.Time{}, err
+		}
+
+		return time.Unix(sec, 0), nil
+	}
+
+	return time.Time{}, fmt.Errorf("btime not found in /proc/stat")
+}
@@ -1,6 +1,9 @@
 package pidfile
 
-import "syscall"
+import (
+	"syscall"
+	"time"
+)
 
 const (
 	processQueryLimitedInformation = 0x1000
@@ -21,3 +24,8 @@ func processExists(pid int) bool {
 	}
 	return true
 }
+
+// processStartTime windows 暂不支持获取进程的启动时间
+func processStartTime(pid int) (time.Time, error) {
+	return time.Time{}, errUnsupported
+}
@@ -0,0 +1,289 @@
+package process
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/mylxsw/asteria/log"
+	"github.com/mylxsw/go-toolkit/pidfile"
+)
+
+// AdoptPolicy Manager 开始运行时，对上一次运行遗留下来的、仍在运行的进程的处理方式
+type AdoptPolicy string
+
+const (
+	// AdoptRunning 接管仍在运行的进程，不会重复启动
+	AdoptRunning AdoptPolicy = "adopt"
+	// AdoptTerminate 结束仍在运行的进程，然后重新启动
+	AdoptTerminate AdoptPolicy = "terminate"
+)
+
+// adoptCheckInterval 检查被接管的进程是否仍在运行的间隔
+const adoptCheckInterval = 200 * time.Millisecond
+
+// SetPIDDir 设置记录进程 pid 的目录，进程启动后 pid 被写入 <dir>/<program>-<index>.pid，进程退出后删除。
+// Manager 开始运行（Watch）时，根据这些 pid 文件查找上一次运行遗留下来的进程（例如 Manager 所在的程序意外退出），
+// 通过 /proc 确认进程仍在运行并且命令行与程序配置一致后，按照 policy 接管或者结束这些进程，避免重复启动。
+// 无法通过 /proc 确认进程身份的平台上只会删除遗留的 pid 文件。需要在 Watch 之前调用。
+//
+// 设置 pid 目录后，进程的输出不再通过管道传递给 Manager，而是直接写入程序的日志文件（没有配置日志文件时丢弃），
+// 保证 Manager 退出后进程仍然可以正常输出。因此这些进程的输出不会出现在 Tail 和 OutputHandler 中，
+// 日志文件也不会按照 LogMaxBytes 轮转（可以使用 logrotate 的 copytruncate 方式轮转）
+func (manager *Manager) SetPIDDir(dir string, policy AdoptPolicy) error {
+	switch policy {
+	case AdoptRunning, AdoptTerminate:
+	default:
+		return fmt.Errorf("invalid adopt policy %q", policy)
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	manager.lock.Lock()
+	defer manager.lock.Unlock()
+
+	manager.pidDir, manager.adoptPolicy = dir, policy
+	for _, program := range manager.programs {
+		for _, process := range program.processes {
+			manager.initProcess(process)
+		}
+	}
+
+	return nil
+}
+
+// initProcess 初始化 Manager 创建的进程，调用前需要持有 manager.lock
+func (manager *Manager) initProcess(process *Process) {
+	process.stateListener = manager.notifier.push
+
+	process.lock.Lock()
+	defer process.lock.Unlock()
+
+	process.pidFile = ""
+	if manager.pidDir != "" {
+		process.pidFile = filepath.Join(manager.pidDir, fmt.Sprintf("%s-%d.pid", process.getProgram().Name, process.index))
+	}
+}
+
+// getPIDFile 返回记录进程 pid 的文件，没有设置 pid 目录时为空
+func (process *Process) getPIDFile() string {
+	process.lock.Lock()
+	defer process.lock.Unlock()
+
+	return process.pidFile
+}
+
+// recoverProcesses 根据 pid 文件接管或者结束上一次运行遗留下来的进程，调用前需要持有 manager.lock
+func (manager *Manager) recoverProcesses() {
+	if manager.pidDir == "" {
+		return
+	}
+
+	for _, program := range manager.sortedPrograms() {
+		for _, process := range program.processes {
+			manager.recoverProcess(program, process)
+		}
+	}
+}
+
+// recoverProcess 根据 pid 文件接管或者结束上一次运行遗留下来的进程，调用前需要持有 manager.lock
+func (manager *Manager) recoverProcess(program *Program, process *Process) {
+	path := process.getPIDFile()
+
+	pid, err := pidfile.ReadPIDFile(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Warningf("read pid file %s failed: %s", path, err)
+			_ = os.Remove(path)
+		}
+		return
+	}
+
+	if !process.matches(pid) {
+		log.Debugf("remove stale pid file %s of process %s (pid %d)", path, process.GetName(), pid)
+		_ = os.Remove(path)
+		return
+	}
+
+	if manager.adoptPolicy == AdoptTerminate {
+		log.Infof("terminate orphan process %s (pid %d)", process.GetName(), pid)
+		process.terminateOrphan(pid, manager.closeTimeout)
+		_ = os.Remove(path)
+		return
+	}
+
+	log.Infof("adopt running process %s (pid %d)", process.GetName(), pid)
+
+	process.lock.Lock()
+	process.running = true
+	process.resetLocked()
+	process.lock.Unlock()
+
+	program.active = true
+
+	restartProcess, watchDone := manager.restartProcess, manager.watchDone
+	go func() {
+		restartSignal := <-process.adopt(pid)
+
+		select {
+		case restartProcess <- restartSignal:
+		case <-watchDone:
+		}
+	}()
+}
+
+// matches 判断 pid 对应的进程是否仍在运行，并且命令行与进程的命令一致，避免 pid 被其它进程复用
+func (process *Process) matches(pid int) bool {
+	cmdline, err := processCmdline(pid)
+	if err != nil || len(cmdline) == 0 {
+		return false
+	}
+
+	command, args := process.GetCommand(), process.GetArgs()
+	if len(cmdline) != len(args)+1 {
+		return false
+	}
+
+	if cmdline[0] != command && filepath.Base(cmdline[0]) != filepath.Base(command) {
+		return false
+	}
+
+	for i, arg := range args {
+		if cmdline[i+1] != arg {
+			return false
+		}
+	}
+
+	return true
+}
+
+// adopt 接管一个仍在运行的进程，该进程不是当前进程的子进程，只能通过定期检查判断其是否已经退出
+func (process *Process) adopt(pid int) <-chan *Process {
+	startTime := time.Now()
+	exited := make(chan struct{})
+
+	process.lock.Lock()
+	process.pid = pid
+	process.exited = exited
+	process.startedAt = startTime
+	process.resetHealthLocked()
+	process.setStateLocked(StateRunning)
+	process.lock.Unlock()
+
+	go func() {
+		defer process.finish(startTime, exited)
+
+		watchCtx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		process.watch(watchCtx, pid)
+
+		ticker := time.NewTicker(adoptCheckInterval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			if !process.matches(pid) {
+				break
+			}
+		}
+
+		// 被接管的进程不是当前进程的子进程，无法获取其退出码
+		process.SetLastErrorMessage("adopted process exited")
+		process.setExitCode(-1)
+	}()
+
+	return process.stat
+}
+
+// terminateOrphan 结束上一次运行遗留下来的进程，超时后向整个进程组发送 SIGKILL 信号
+func (process *Process) terminateOrphan(pid int, timeout time.Duration) {
+	program := process.getProgram()
+	if program.StopTimeout > 0 {
+		timeout = program.StopTimeout
+	}
+
+	waitExit := func(timeout time.Duration) bool {
+		deadline := time.Now().Add(timeout)
+		for process.matches(pid) {
+			if time.Now().After(deadline) {
+				return false
+			}
+
+			time.Sleep(adoptCheckInterval / 4)
+		}
+
+		return true
+	}
+
+	if err := signalGroup(pid, program.StopSignal); err != nil {
+		log.Warningf("send signal %s to orphan process %s failed: %s", program.StopSignal, process.GetName(), err)
+	} else if waitExit(timeout) {
+		return
+	}
+
+	if err := signalGroup(pid, syscall.SIGKILL); err != nil {
+		log.Warningf("kill orphan process %s failed: %s", process.GetName(), err)
+	}
+
+	if !waitExit(killTimeout) {
+		log.Errorf("orphan process %s is still alive after killed", process.GetName())
+	}
+}
+
+// outputFiles 将进程的标准输出和标准错误输出直接重定向到程序的日志文件，用于设置了 pid 目录的进程：
+// 使用管道时，Manager 意外退出后管道的读端随之关闭，被接管的进程再输出时会收到 SIGPIPE 信号而退出。
+// 返回的函数用于在进程启动后关闭当前进程持有的文件
+func (process *Process) outputFiles(cmd *exec.Cmd) (func(), error) {
+	program := process.getProgram()
+
+	stdout, err := openOutput(program.StdoutLogFile)
+	if err != nil {
+		return nil, err
+	}
+
+	stderr := stdout
+	if !program.RedirectStderr {
+		if stderr, err = openOutput(program.StderrLogFile); err != nil {
+			_ = stdout.Close()
+			return nil, err
+		}
+	}
+
+	cmd.Stdout, cmd.Stderr = stdout, stderr
+	return func() {
+		_ = stdout.Close()
+		_ = stderr.Close()
+	}, nil
+}
+
+// openOutput 以追加方式打开进程的输出文件，path 为空时打开 /dev/null
+func openOutput(path string) (*os.File, error) {
+	if path == "" {
+		return os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return nil, err
+	}
+
+	return os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+}
+
+// writePIDFile 将进程的 pid 写入 pid 文件
+func (process *Process) writePIDFile(pid int) {
+	path := process.getPIDFile()
+
+	if path == "" {
+		return
+	}
+
+	if err := pidfile.Write(path, pid); err != nil {
+		log.Warningf("write pid file %s failed: %s", path, err)
+	}
+}
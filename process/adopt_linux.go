@@ -0,0 +1,31 @@
+//go:build linux
+// +build linux
+
+package process
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+)
+
+// processCmdline 从 /proc/<pid>/cmdline 中读取进程的命令行，僵尸进程的命令行为空
+func processCmdline(pid int) ([]string, error) {
+	data, err := ioutil.ReadFile(filepath.Join("/proc", strconv.Itoa(pid), "cmdline"))
+	if err != nil {
+		return nil, err
+	}
+
+	data = bytes.TrimSuffix(data, []byte{0})
+	if len(data) == 0 {
+		return nil, nil
+	}
+
+	args := make([]string, 0)
+	for _, arg := range bytes.Split(data, []byte{0}) {
+		args = append(args, string(arg))
+	}
+
+	return args, nil
+}
@@ -0,0 +1,9 @@
+//go:build !linux
+// +build !linux
+
+package process
+
+// processCmdline 非 Linux 平台没有 /proc 文件系统，无法确认进程身份
+func processCmdline(pid int) ([]string, error) {
+	return nil, ErrResourceUnsupported
+}
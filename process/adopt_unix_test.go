@@ -0,0 +1,269 @@
+//go:build !windows
+// +build !windows
+
+package process
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/mylxsw/go-toolkit/pidfile"
+)
+
+// startOrphan 启动一个进程并写入 pid 文件，模拟上一次运行遗留下来的进程
+func startOrphan(t *testing.T, path string) (*exec.Cmd, chan struct{}) {
+	cmd := exec.Command("/bin/sleep", "30")
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	exited := make(chan struct{})
+	go func() {
+		_ = cmd.Wait()
+		close(exited)
+	}()
+
+	if err := pidfile.Write(path, cmd.Process.Pid); err != nil {
+		t.Fatal(err)
+	}
+
+	return cmd, exited
+}
+
+func TestAdoptRunningProcesses(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("process adoption requires /proc")
+	}
+
+	dir, err := ioutil.TempDir("", "adopt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	orphan, orphanExited := startOrphan(t, filepath.Join(dir, "sleep-0.pid"))
+	defer orphan.Process.Kill()
+
+	// pid 对应的进程命令行与程序不一致，视为过期的 pid 文件
+	if err := pidfile.Write(filepath.Join(dir, "sleep-1.pid"), os.Getpid()); err != nil {
+		t.Fatal(err)
+	}
+
+	program := NewProgram("sleep", "/bin/sleep 30", "", 2)
+	program.StartSecs = 0
+	program.BackoffInitial, program.BackoffMax = 10*time.Millisecond, 10*time.Millisecond
+
+	manager := NewManager(time.Second, nil)
+	if err := manager.AddPrograms(program); err != nil {
+		t.Fatal(err)
+	}
+	if err := manager.SetPIDDir(dir, AdoptRunning); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		manager.Watch(ctx)
+		close(done)
+	}()
+
+	procs := program.Processes()
+	waitFor(t, "processes running", program.ready)
+
+	if pid := procs[0].GetPID(); pid != orphan.Process.Pid {
+		t.Errorf("expect orphan process %d adopted, got %d", orphan.Process.Pid, pid)
+	}
+
+	pid := procs[1].GetPID()
+	if pid == os.Getpid() {
+		t.Errorf("expect process started instead of adopting unrelated process")
+	}
+	if recorded, err := pidfile.ReadPIDFile(filepath.Join(dir, "sleep-1.pid")); err != nil || recorded != pid {
+		t.Errorf("expect pid %d recorded, got %d, %v", pid, recorded, err)
+	}
+
+	// 被接管的进程退出后，由 Manager 重新启动
+	_ = orphan.Process.Kill()
+	<-orphanExited
+	waitFor(t, "adopted process restarted", func() bool {
+		status := procs[0].Status()
+		return status.State == StateRunning && status.PID > 0 && status.PID != orphan.Process.Pid
+	})
+
+	cancel()
+	<-done
+
+	for _, name := range []string{"sleep-0.pid", "sleep-1.pid"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); !os.IsNotExist(err) {
+			t.Errorf("expect pid file %s removed after manager stopped, got %v", name, err)
+		}
+	}
+}
+
+func TestAdoptTerminate(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("process adoption requires /proc")
+	}
+
+	dir, err := ioutil.TempDir("", "adopt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	orphan, orphanExited := startOrphan(t, filepath.Join(dir, "sleep-0.pid"))
+	defer orphan.Process.Kill()
+
+	program := NewProgram("sleep", "/bin/sleep 30", "", 1)
+	program.StartSecs = 0
+
+	manager := NewManager(time.Second, nil)
+	if err := manager.SetPIDDir(dir, AdoptTerminate); err != nil {
+		t.Fatal(err)
+	}
+	if err := manager.AddPrograms(program); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		manager.Watch(ctx)
+		close(done)
+	}()
+
+	select {
+	case <-orphanExited:
+	case <-time.After(3 * time.Second):
+		t.Fatalf("expect orphan process terminated")
+	}
+
+	proc := program.Processes()[0]
+	waitFor(t, "process started", program.ready)
+	if pid := proc.GetPID(); pid == orphan.Process.Pid {
+		t.Errorf("expect a fresh process started")
+	}
+
+	if recorded, err := pidfile.ReadPIDFile(filepath.Join(dir, "sleep-0.pid")); err != nil || recorded != proc.GetPID() {
+		t.Errorf("expect pid file updated, got %d, %v", recorded, err)
+	}
+
+	cancel()
+	<-done
+
+	if err := manager.SetPIDDir(dir, "unknown"); err == nil {
+		t.Errorf("expect error for invalid adopt policy")
+	}
+}
+
+// tickerProgram 持续输出的程序，输出直接写入 dir 下的日志文件
+func tickerProgram(dir string) *Program {
+	program := NewProgram("ticker", `/bin/sh -c 'while true; do echo tick; echo tock >&2; sleep 0.05; done'`, "", 1)
+	program.StartSecs = 0
+	program.StdoutLogFile = filepath.Join(dir, "ticker.log")
+
+	return program
+}
+
+func TestAdoptProcessWithOutput(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("process adoption requires /proc")
+	}
+
+	dir, err := ioutil.TempDir("", "adopt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// 在子进程中运行 Manager，强制结束后遗留下持续输出的进程
+	supervisor := exec.Command(os.Args[0], "-test.run=TestAdoptSupervisorHelper")
+	supervisor.Env = append(os.Environ(), "PROCESS_ADOPT_HELPER="+dir)
+	if err := supervisor.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	pidPath := filepath.Join(dir, "ticker-0.pid")
+	logPath := filepath.Join(dir, "ticker.log")
+	waitFor(t, "orphan process started", func() bool {
+		data, _ := ioutil.ReadFile(logPath)
+		_, err := pidfile.ReadPIDFile(pidPath)
+		return err == nil && strings.Count(string(data), "tick") >= 2
+	})
+
+	orphanPID, _ := pidfile.ReadPIDFile(pidPath)
+	defer syscall.Kill(-orphanPID, syscall.SIGKILL)
+
+	if link, err := os.Readlink(filepath.Join("/proc", strconv.Itoa(orphanPID), "fd", "2")); err != nil || link != os.DevNull {
+		t.Errorf("expect stderr discarded without log file, got %s, %v", link, err)
+	}
+
+	_ = supervisor.Process.Kill()
+	_ = supervisor.Wait()
+
+	program := tickerProgram(dir)
+	manager := NewManager(time.Second, nil)
+	if err := manager.AddPrograms(program); err != nil {
+		t.Fatal(err)
+	}
+	if err := manager.SetPIDDir(dir, AdoptRunning); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		manager.Watch(ctx)
+		close(done)
+	}()
+
+	proc := program.Processes()[0]
+	waitFor(t, "process adopted", program.ready)
+	if pid := proc.GetPID(); pid != orphanPID {
+		t.Errorf("expect orphan process %d adopted, got %d", orphanPID, pid)
+	}
+
+	// Manager 退出后，被接管的进程继续输出，不会因为 SIGPIPE 退出
+	before, _ := ioutil.ReadFile(logPath)
+	time.Sleep(300 * time.Millisecond)
+	after, _ := ioutil.ReadFile(logPath)
+
+	if !processAlive(orphanPID) || proc.GetPID() != orphanPID {
+		t.Errorf("expect adopted process still running, status %+v", proc.Status())
+	}
+	if len(after) <= len(before) {
+		t.Errorf("expect adopted process keeps writing output")
+	}
+
+	cancel()
+	<-done
+}
+
+// TestAdoptSupervisorHelper 作为 TestAdoptProcessWithOutput 的子进程运行 Manager，直到被强制结束
+func TestAdoptSupervisorHelper(t *testing.T) {
+	dir := os.Getenv("PROCESS_ADOPT_HELPER")
+	if dir == "" {
+		t.Skip("helper process")
+	}
+
+	manager := NewManager(time.Second, nil)
+	if err := manager.AddPrograms(tickerProgram(dir)); err != nil {
+		t.Fatal(err)
+	}
+	if err := manager.SetPIDDir(dir, AdoptRunning); err != nil {
+		t.Fatal(err)
+	}
+
+	manager.Watch(context.Background())
+}
@@ -0,0 +1,104 @@
+package process
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+)
+
+// ControlClient 控制服务客户端
+type ControlClient struct {
+	lock    sync.Mutex
+	conn    net.Conn
+	encoder *json.Encoder
+	decoder *json.Decoder
+	id      uint64
+}
+
+// DialControl 连接到 path 指定的控制服务
+func DialControl(path string) (*ControlClient, error) {
+	conn, err := net.Dial("unix", path)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ControlClient{
+		conn:    conn,
+		encoder: json.NewEncoder(conn),
+		decoder: json.NewDecoder(conn),
+	}, nil
+}
+
+// Close 关闭连接
+func (client *ControlClient) Close() error {
+	return client.conn.Close()
+}
+
+// Status 查询进程状态，name 为程序名称或者进程名称，为空时返回所有进程的状态
+func (client *ControlClient) Status(name string) ([]ProcessStatus, error) {
+	var status []ProcessStatus
+	err := client.call(MethodStatus, rpcParams{Name: name}, &status)
+
+	return status, err
+}
+
+// Start 启动程序
+func (client *ControlClient) Start(name string) error {
+	return client.call(MethodStart, rpcParams{Name: name}, nil)
+}
+
+// Stop 停止程序
+func (client *ControlClient) Stop(name string) error {
+	return client.call(MethodStop, rpcParams{Name: name}, nil)
+}
+
+// Restart 重启程序
+func (client *ControlClient) Restart(name string) error {
+	return client.call(MethodRestart, rpcParams{Name: name}, nil)
+}
+
+// RollingRestart 滚动重启程序，每次重启 batch 个进程，timeout 为 0 时不限制超时时间
+func (client *ControlClient) RollingRestart(name string, batch int, timeout time.Duration) error {
+	return client.call(MethodRollingRestart, rpcParams{Name: name, Batch: batch, Timeout: timeout}, nil)
+}
+
+// Tail 获取程序或者进程最近的 lines 行输出
+func (client *ControlClient) Tail(name string, lines int) ([]OutputLine, error) {
+	var result []OutputLine
+	err := client.call(MethodTail, rpcParams{Name: name, Lines: lines}, &result)
+
+	return result, err
+}
+
+// call 发送请求并等待响应，result 不为 nil 时将结果解析到 result 中
+func (client *ControlClient) call(method string, params rpcParams, result interface{}) error {
+	client.lock.Lock()
+	defer client.lock.Unlock()
+
+	client.id++
+	if err := client.encoder.Encode(rpcRequest{ID: client.id, Method: method, Params: params}); err != nil {
+		return err
+	}
+
+	var resp rpcResponse
+	if err := client.decoder.Decode(&resp); err != nil {
+		return err
+	}
+
+	if resp.ID != client.id {
+		return fmt.Errorf("unexpected response id %d, expect %d", resp.ID, client.id)
+	}
+
+	if resp.Error != "" {
+		return errors.New(resp.Error)
+	}
+
+	if result != nil && len(resp.Result) > 0 {
+		return json.Unmarshal(resp.Result, result)
+	}
+
+	return nil
+}
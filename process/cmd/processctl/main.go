@@ -0,0 +1,208 @@
+// processctl 是 process.ControlServer 的命令行客户端，用法与 supervisorctl 类似
+//
+//	processctl -s /var/run/process.sock status
+//	processctl restart queue-worker
+//	processctl tail -n 50 queue-worker/0
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/mylxsw/go-toolkit/process"
+)
+
+const usage = `Usage: processctl [-s socket] <command> [args]
+
+Commands:
+  status [name]           show status of all processes, or processes of a program
+  start <name>            start a program
+  stop <name>             stop a program
+  restart <name>          restart a program
+  rolling-restart [-b batch] [-t timeout] <name>
+                          restart processes of a program batch by batch, waiting for each batch to be ready
+  tail [-n lines] <name>  show recent output of a program or a process (program/index)
+
+Options:
+`
+
+func main() {
+	defaultSocket := os.Getenv("PROCESSCTL_SOCKET")
+	if defaultSocket == "" {
+		defaultSocket = "/var/run/process.sock"
+	}
+
+	socket := flag.String("s", defaultSocket, "control socket path, env PROCESSCTL_SOCKET")
+	flag.Usage = func() {
+		fmt.Fprint(os.Stderr, usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() < 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	client, err := process.DialControl(*socket)
+	if err != nil {
+		fail(err)
+	}
+	defer client.Close()
+
+	command, args := flag.Arg(0), flag.Args()[1:]
+	switch command {
+	case "status":
+		name := ""
+		if len(args) > 0 {
+			name = args[0]
+		}
+
+		status, err := client.Status(name)
+		if err != nil {
+			fail(err)
+		}
+		printStatus(status)
+	case "start", "stop", "restart":
+		if len(args) < 1 {
+			fail(fmt.Errorf("%s: program name is required", command))
+		}
+
+		for _, name := range args {
+			var err error
+			switch command {
+			case "start":
+				err = client.Start(name)
+			case "stop":
+				err = client.Stop(name)
+			default:
+				err = client.Restart(name)
+			}
+
+			if err != nil {
+				fail(fmt.Errorf("%s %s: %s", command, name, err))
+			}
+			fmt.Printf("%s: %s ok\n", name, command)
+		}
+	case "rolling-restart":
+		rollingFlags := flag.NewFlagSet("rolling-restart", flag.ExitOnError)
+		batch := rollingFlags.Int("b", 1, "number of processes restarted at a time")
+		timeout := rollingFlags.Duration("t", 0, "timeout of the whole rolling restart, 0 means no timeout")
+		_ = rollingFlags.Parse(args)
+		if rollingFlags.NArg() < 1 {
+			fail(fmt.Errorf("rolling-restart: program name is required"))
+		}
+
+		name := rollingFlags.Arg(0)
+		if err := client.RollingRestart(name, *batch, *timeout); err != nil {
+			fail(fmt.Errorf("rolling-restart %s: %s", name, err))
+		}
+		fmt.Printf("%s: rolling-restart ok\n", name)
+	case "tail":
+		tailFlags := flag.NewFlagSet("tail", flag.ExitOnError)
+		lines := tailFlags.Int("n", 20, "number of lines")
+		_ = tailFlags.Parse(args)
+		if tailFlags.NArg() < 1 {
+			fail(fmt.Errorf("tail: name is required"))
+		}
+
+		output, err := client.Tail(tailFlags.Arg(0), *lines)
+		if err != nil {
+			fail(err)
+		}
+
+		for _, line := range output {
+			fmt.Printf("%s %s [%s] %s\n", line.Time.Format("2006-01-02 15:04:05"), line.Process, line.Type, line.Line)
+		}
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+}
+
+func printStatus(status []process.ProcessStatus) {
+	var template = "%-24s %-10s %-10s %-8s %-14s %-7s %-9s %-5s %-8s %-6s %s\n"
+	fmt.Printf(color.New(color.BgBlue).Sprint(template), "name", "state", "health", "pid", "uptime", "cpu", "mem", "fds", "restarts", "exit", "error")
+
+	for _, st := range status {
+		pid := "-"
+		if st.PID > 0 {
+			pid = strconv.Itoa(st.PID)
+		}
+
+		uptime := "-"
+		if st.Uptime > 0 {
+			uptime = st.Uptime.Truncate(time.Second).String()
+		}
+
+		state := fmt.Sprintf("%-10s", st.State)
+		switch st.State {
+		case process.StateRunning:
+			state = color.GreenString(state)
+		case process.StateBackoff, process.StateFatal:
+			state = color.RedString(state)
+		}
+
+		health := fmt.Sprintf("%-10s", "-")
+		switch st.Health {
+		case process.HealthHealthy:
+			health = color.GreenString("%-10s", st.Health)
+		case process.HealthUnhealthy:
+			health = color.RedString("%-10s", st.Health)
+		case process.HealthUnknown:
+			health = fmt.Sprintf("%-10s", st.Health)
+		}
+
+		cpu, mem, fds := "-", "-", "-"
+		if st.PID > 0 && st.RSS > 0 {
+			cpu = fmt.Sprintf("%.1f%%", st.CPUPercent)
+			mem = formatBytes(st.RSS)
+			fds = strconv.Itoa(st.OpenFDs)
+		}
+
+		lastError := st.LastError
+		if st.Health == process.HealthUnhealthy {
+			lastError = st.HealthError
+		}
+
+		fmt.Printf(
+			"%-24s %s %s %-8s %-14s %-7s %-9s %-5s %-8d %-6d %s\n",
+			st.Name,
+			state,
+			health,
+			pid,
+			uptime,
+			cpu,
+			mem,
+			fds,
+			st.Restarts,
+			st.ExitCode,
+			lastError,
+		)
+	}
+}
+
+// formatBytes 将字节数格式化为便于阅读的形式
+func formatBytes(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%dB", size)
+	}
+
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+
+	return fmt.Sprintf("%.1f%cB", float64(size)/float64(div), "KMGT"[exp])
+}
+
+func fail(err error) {
+	fmt.Fprintln(os.Stderr, color.RedString("error: %s", err))
+	os.Exit(1)
+}
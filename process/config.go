@@ -0,0 +1,747 @@
+package process
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/ini.v1"
+	"gopkg.in/yaml.v2"
+)
+
+// ConfigFormat 配置文件格式
+type ConfigFormat string
+
+const (
+	// FormatJSON JSON 格式
+	FormatJSON ConfigFormat = "json"
+	// FormatYAML YAML 格式
+	FormatYAML ConfigFormat = "yaml"
+	// FormatINI supervisord 风格的 INI 格式，每个程序对应一个 [program:名称] 段
+	FormatINI ConfigFormat = "ini"
+)
+
+// ConfigError 配置错误，指明出错的程序及字段
+type ConfigError struct {
+	// Program 程序名称，没有名称时为其在配置文件中的位置
+	Program string
+	Field   string
+	Err     error
+}
+
+// Error 实现 error 接口
+func (e *ConfigError) Error() string {
+	if e.Field == "" {
+		return fmt.Sprintf("program %s: %s", e.Program, e.Err)
+	}
+
+	return fmt.Sprintf("program %s: %s: %s", e.Program, e.Field, e.Err)
+}
+
+// ConfigErrors 多个配置错误
+type ConfigErrors []*ConfigError
+
+// Error 实现 error 接口
+func (errs ConfigErrors) Error() string {
+	messages := make([]string, len(errs))
+	for i, err := range errs {
+		messages[i] = err.Error()
+	}
+
+	return strings.Join(messages, "; ")
+}
+
+// Config 进程管理器配置
+type Config struct {
+	Programs []*Program
+}
+
+// programConfig 配置文件中单个程序的配置，字段命名与 supervisord 保持一致，时间单位为秒
+type programConfig struct {
+	Name         string            `json:"name" yaml:"name"`
+	Command      string            `json:"command" yaml:"command"`
+	User         string            `json:"user" yaml:"user"`
+	NumProcs     *int              `json:"numprocs" yaml:"numprocs"`
+	Environment  map[string]string `json:"environment" yaml:"environment"`
+	Directory    string            `json:"directory" yaml:"directory"`
+	AutoStart    *bool             `json:"autostart" yaml:"autostart"`
+	StartSecs    *int              `json:"startsecs" yaml:"startsecs"`
+	StartRetries *int              `json:"startretries" yaml:"startretries"`
+	StopSignal   string            `json:"stopsignal" yaml:"stopsignal"`
+	StopWaitSecs *int              `json:"stopwaitsecs" yaml:"stopwaitsecs"`
+	Priority     *int              `json:"priority" yaml:"priority"`
+	// AutoRestart 重启策略，可以为 always、on-failure、never，或者 supervisord 风格的 true、unexpected、false
+	AutoRestart    interface{} `json:"autorestart" yaml:"autorestart"`
+	ExitCodes      []int       `json:"exitcodes" yaml:"exitcodes"`
+	BackoffSecs    *int        `json:"backoffsecs" yaml:"backoffsecs"`
+	MaxBackoffSecs *int        `json:"maxbackoffsecs" yaml:"maxbackoffsecs"`
+	MaxRestarts    *int        `json:"maxrestarts" yaml:"maxrestarts"`
+	ResetSecs      *int        `json:"resetsecs" yaml:"resetsecs"`
+	StdoutLogFile  string      `json:"stdout_logfile" yaml:"stdout_logfile"`
+	StderrLogFile  string      `json:"stderr_logfile" yaml:"stderr_logfile"`
+	RedirectStderr *bool       `json:"redirect_stderr" yaml:"redirect_stderr"`
+	// LogMaxBytes 日志文件最大大小，可以为字节数或者 50MB 这样带单位的字符串
+	LogMaxBytes interface{} `json:"logfile_maxbytes" yaml:"logfile_maxbytes"`
+	LogBackups  *int        `json:"logfile_backups" yaml:"logfile_backups"`
+	TailLines   *int        `json:"tail_lines" yaml:"tail_lines"`
+
+	DependsOn   []string           `json:"depends_on" yaml:"depends_on"`
+	HealthCheck *healthCheckConfig `json:"healthcheck" yaml:"healthcheck"`
+	Resources   *resourcesConfig   `json:"resources" yaml:"resources"`
+	// Sockets 监听 socket 列表，格式为 [name=]network://address，例如 http=tcp://:8080
+	Sockets []string `json:"sockets" yaml:"sockets"`
+
+	// index 程序在配置文件中的位置
+	index int
+}
+
+// healthCheckConfig 健康检查配置，时间单位为秒
+type healthCheckConfig struct {
+	Type      string `json:"type" yaml:"type"`
+	Target    string `json:"target" yaml:"target"`
+	Interval  *int   `json:"interval" yaml:"interval"`
+	Timeout   *int   `json:"timeout" yaml:"timeout"`
+	Threshold *int   `json:"threshold" yaml:"threshold"`
+}
+
+// resourcesConfig 资源限制配置，大小可以为字节数或者 512MB 这样带单位的字符串，时间单位为秒
+type resourcesConfig struct {
+	MaxOpenFiles    *uint64     `json:"max_open_files" yaml:"max_open_files"`
+	MaxProcesses    *uint64     `json:"max_processes" yaml:"max_processes"`
+	MaxAddressSpace interface{} `json:"max_address_space" yaml:"max_address_space"`
+	MaxCoreSize     interface{} `json:"max_core_size" yaml:"max_core_size"`
+	Cgroup          string      `json:"cgroup" yaml:"cgroup"`
+	MemoryMax       interface{} `json:"memory_max" yaml:"memory_max"`
+	CPUMax          *float64    `json:"cpu_max" yaml:"cpu_max"`
+	MemoryThreshold interface{} `json:"memory_threshold" yaml:"memory_threshold"`
+	SampleInterval  *int        `json:"sample_interval" yaml:"sample_interval"`
+}
+
+// LoadConfigFile 从文件中加载配置，根据文件扩展名判断格式：.json、.yaml/.yml、.ini/.conf
+func LoadConfigFile(path string) (*Config, error) {
+	var format ConfigFormat
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		format = FormatJSON
+	case ".yaml", ".yml":
+		format = FormatYAML
+	case ".ini", ".conf":
+		format = FormatINI
+	default:
+		return nil, fmt.Errorf("unsupported config file format: %s", path)
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseConfig(data, format)
+}
+
+// ParseConfig 解析配置，配置不合法时返回 ConfigErrors
+//
+// JSON 和 YAML 格式的配置为包含 programs 数组的对象
+//
+//	programs:
+//	  - name: queue-worker
+//	    command: /usr/local/bin/worker --queue "default high"
+//	    numprocs: 2
+//	    environment:
+//	      APP_ENV: production
+//	    directory: /srv/app
+//	    startsecs: 3
+//	    stopsignal: QUIT
+//
+// INI 格式与 supervisord 一致
+//
+//	[program:queue-worker]
+//	command=/usr/local/bin/worker --queue "default high"
+//	numprocs=2
+//	environment=APP_ENV="production",LANG="en_US.UTF-8"
+func ParseConfig(data []byte, format ConfigFormat) (*Config, error) {
+	var configs []programConfig
+	var errs ConfigErrors
+
+	switch format {
+	case FormatJSON:
+		configs, errs = parseJSONConfig(data)
+	case FormatYAML:
+		configs, errs = parseYAMLConfig(data)
+	case FormatINI:
+		configs, errs = parseINIConfig(data)
+	default:
+		return nil, fmt.Errorf("unsupported config format: %s", format)
+	}
+
+	config := &Config{Programs: make([]*Program, 0, len(configs))}
+	names := make(map[string]bool)
+	for _, conf := range configs {
+		program, err := conf.toProgram()
+		if err != nil {
+			errs = append(errs, err.(ConfigErrors)...)
+			continue
+		}
+
+		if names[program.Name] {
+			errs = append(errs, &ConfigError{Program: program.Name, Field: "name", Err: fmt.Errorf("duplicate program name")})
+			continue
+		}
+
+		names[program.Name] = true
+		config.Programs = append(config.Programs, program)
+	}
+
+	if len(errs) > 0 {
+		return nil, errs
+	}
+
+	programs := make(map[string]*Program, len(config.Programs))
+	for _, program := range config.Programs {
+		programs[program.Name] = program
+	}
+
+	if errs := validateDependencies(programs); len(errs) > 0 {
+		return nil, errs
+	}
+
+	return config, nil
+}
+
+func parseJSONConfig(data []byte) ([]programConfig, ConfigErrors) {
+	var file struct {
+		Programs []json.RawMessage `json:"programs"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, ConfigErrors{{Program: "*", Err: err}}
+	}
+
+	configs := make([]programConfig, 0, len(file.Programs))
+	errs := make(ConfigErrors, 0)
+	for i, raw := range file.Programs {
+		var conf programConfig
+
+		decoder := json.NewDecoder(bytes.NewReader(raw))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&conf); err != nil {
+			errs = append(errs, &ConfigError{Program: entryName(raw, i), Err: err})
+			continue
+		}
+
+		conf.index = i
+		configs = append(configs, conf)
+	}
+
+	return configs, errs
+}
+
+func parseYAMLConfig(data []byte) ([]programConfig, ConfigErrors) {
+	var file struct {
+		Programs []map[string]interface{} `yaml:"programs"`
+	}
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, ConfigErrors{{Program: "*", Err: err}}
+	}
+
+	configs := make([]programConfig, 0, len(file.Programs))
+	errs := make(ConfigErrors, 0)
+	for i, entry := range file.Programs {
+		name := fmt.Sprintf("#%d", i)
+		if n, ok := entry["name"].(string); ok && n != "" {
+			name = n
+		}
+
+		// 逐个程序重新解析，以便错误信息能够指明出错的程序
+		raw, err := yaml.Marshal(entry)
+		if err != nil {
+			errs = append(errs, &ConfigError{Program: name, Err: err})
+			continue
+		}
+
+		conf := programConfig{index: i}
+		if err := yaml.UnmarshalStrict(raw, &conf); err != nil {
+			errs = append(errs, &ConfigError{Program: name, Err: err})
+			continue
+		}
+
+		configs = append(configs, conf)
+	}
+
+	return configs, errs
+}
+
+func parseINIConfig(data []byte) ([]programConfig, ConfigErrors) {
+	// command 中可能包含 ; 或者 #，因此不支持行内注释
+	f, err := ini.LoadSources(ini.LoadOptions{IgnoreInlineComment: true}, data)
+	if err != nil {
+		return nil, ConfigErrors{{Program: "*", Err: err}}
+	}
+
+	configs := make([]programConfig, 0)
+	errs := make(ConfigErrors, 0)
+	for i, section := range f.Sections() {
+		if !strings.HasPrefix(section.Name(), "program:") {
+			continue
+		}
+
+		conf := programConfig{Name: strings.TrimSpace(strings.TrimPrefix(section.Name(), "program:")), index: i}
+		sectionErrs := make(ConfigErrors, 0)
+		addErr := func(field string, err error) {
+			sectionErrs = append(sectionErrs, &ConfigError{Program: conf.Name, Field: field, Err: err})
+		}
+
+		intValue := func(key string) *int {
+			val, err := section.Key(key).Int()
+			if err != nil {
+				addErr(key, fmt.Errorf("invalid integer %q", section.Key(key).String()))
+				return nil
+			}
+
+			return &val
+		}
+
+		for _, key := range section.Keys() {
+			switch key.Name() {
+			case "command":
+				conf.Command = key.String()
+			case "user":
+				conf.User = key.String()
+			case "directory":
+				conf.Directory = key.String()
+			case "stopsignal":
+				conf.StopSignal = key.String()
+			case "environment":
+				env, err := parseEnvironment(key.String())
+				if err != nil {
+					addErr("environment", err)
+				}
+				conf.Environment = env
+			case "autostart":
+				val, err := key.Bool()
+				if err != nil {
+					addErr("autostart", fmt.Errorf("invalid boolean %q", key.String()))
+				}
+				conf.AutoStart = &val
+			case "numprocs":
+				conf.NumProcs = intValue("numprocs")
+			case "startsecs":
+				conf.StartSecs = intValue("startsecs")
+			case "startretries":
+				conf.StartRetries = intValue("startretries")
+			case "stopwaitsecs":
+				conf.StopWaitSecs = intValue("stopwaitsecs")
+			case "priority":
+				conf.Priority = intValue("priority")
+			case "autorestart":
+				conf.AutoRestart = key.String()
+			case "exitcodes":
+				codes, err := parseExitCodes(key.String())
+				if err != nil {
+					addErr("exitcodes", err)
+				}
+				conf.ExitCodes = codes
+			case "backoffsecs":
+				conf.BackoffSecs = intValue("backoffsecs")
+			case "maxbackoffsecs":
+				conf.MaxBackoffSecs = intValue("maxbackoffsecs")
+			case "maxrestarts":
+				conf.MaxRestarts = intValue("maxrestarts")
+			case "resetsecs":
+				conf.ResetSecs = intValue("resetsecs")
+			case "stdout_logfile":
+				conf.StdoutLogFile = key.String()
+			case "stderr_logfile":
+				conf.StderrLogFile = key.String()
+			case "redirect_stderr":
+				val, err := key.Bool()
+				if err != nil {
+					addErr("redirect_stderr", fmt.Errorf("invalid boolean %q", key.String()))
+				}
+				conf.RedirectStderr = &val
+			case "logfile_maxbytes":
+				conf.LogMaxBytes = key.String()
+			case "logfile_backups":
+				conf.LogBackups = intValue("logfile_backups")
+			case "tail_lines":
+				conf.TailLines = intValue("tail_lines")
+			case "depends_on":
+				conf.DependsOn = make([]string, 0)
+				for _, dep := range strings.Split(key.String(), ",") {
+					if dep = strings.TrimSpace(dep); dep != "" {
+						conf.DependsOn = append(conf.DependsOn, dep)
+					}
+				}
+			case "healthcheck_type", "healthcheck_target", "healthcheck_interval", "healthcheck_timeout", "healthcheck_threshold":
+				if conf.HealthCheck == nil {
+					conf.HealthCheck = &healthCheckConfig{}
+				}
+
+				switch key.Name() {
+				case "healthcheck_type":
+					conf.HealthCheck.Type = key.String()
+				case "healthcheck_target":
+					conf.HealthCheck.Target = key.String()
+				case "healthcheck_interval":
+					conf.HealthCheck.Interval = intValue("healthcheck_interval")
+				case "healthcheck_timeout":
+					conf.HealthCheck.Timeout = intValue("healthcheck_timeout")
+				case "healthcheck_threshold":
+					conf.HealthCheck.Threshold = intValue("healthcheck_threshold")
+				}
+			case "sockets":
+				conf.Sockets = make([]string, 0)
+				for _, spec := range strings.Split(key.String(), ",") {
+					if spec = strings.TrimSpace(spec); spec != "" {
+						conf.Sockets = append(conf.Sockets, spec)
+					}
+				}
+			case "max_open_files", "max_processes", "max_address_space", "max_core_size", "cgroup",
+				"memory_max", "cpu_max", "memory_threshold", "sample_interval":
+				if conf.Resources == nil {
+					conf.Resources = &resourcesConfig{}
+				}
+
+				switch key.Name() {
+				case "max_open_files", "max_processes":
+					val, err := key.Uint64()
+					if err != nil {
+						addErr(key.Name(), fmt.Errorf("invalid number %q", key.String()))
+					}
+					if key.Name() == "max_open_files" {
+						conf.Resources.MaxOpenFiles = &val
+					} else {
+						conf.Resources.MaxProcesses = &val
+					}
+				case "max_address_space":
+					conf.Resources.MaxAddressSpace = key.String()
+				case "max_core_size":
+					conf.Resources.MaxCoreSize = key.String()
+				case "cgroup":
+					conf.Resources.Cgroup = key.String()
+				case "memory_max":
+					conf.Resources.MemoryMax = key.String()
+				case "cpu_max":
+					val, err := key.Float64()
+					if err != nil {
+						addErr("cpu_max", fmt.Errorf("invalid number %q", key.String()))
+					}
+					conf.Resources.CPUMax = &val
+				case "memory_threshold":
+					conf.Resources.MemoryThreshold = key.String()
+				case "sample_interval":
+					conf.Resources.SampleInterval = intValue("sample_interval")
+				}
+			default:
+				addErr(key.Name(), fmt.Errorf("unknown option"))
+			}
+		}
+
+		if len(sectionErrs) > 0 {
+			errs = append(errs, sectionErrs...)
+			continue
+		}
+
+		configs = append(configs, conf)
+	}
+
+	return configs, errs
+}
+
+// parseEnvironment 解析 supervisord 风格的环境变量配置：KEY1="value1",KEY2=value2
+func parseEnvironment(env string) (map[string]string, error) {
+	result := make(map[string]string)
+
+	var key, value strings.Builder
+	var quote rune
+	inValue := false
+	flush := func() error {
+		k := strings.TrimSpace(key.String())
+		if k == "" && !inValue {
+			return nil
+		}
+
+		if k == "" || !inValue {
+			return fmt.Errorf("invalid environment %q", env)
+		}
+
+		result[k] = value.String()
+		key.Reset()
+		value.Reset()
+		inValue = false
+		return nil
+	}
+
+	for _, c := range env {
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			} else {
+				value.WriteRune(c)
+			}
+		case !inValue && c == '=':
+			inValue = true
+		case !inValue:
+			key.WriteRune(c)
+		case c == '"' || c == '\'':
+			quote = c
+		case c == ',':
+			if err := flush(); err != nil {
+				return nil, err
+			}
+		default:
+			value.WriteRune(c)
+		}
+	}
+
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated quote in environment %q", env)
+	}
+
+	if err := flush(); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// toHealthCheck 将配置转换为 HealthCheck
+func (conf *healthCheckConfig) toHealthCheck() *HealthCheck {
+	check := &HealthCheck{
+		Type:   HealthCheckType(strings.ToLower(conf.Type)),
+		Target: conf.Target,
+	}
+
+	if conf.Interval != nil {
+		check.Interval = time.Duration(*conf.Interval) * time.Second
+	}
+	if conf.Timeout != nil {
+		check.Timeout = time.Duration(*conf.Timeout) * time.Second
+	}
+	if conf.Threshold != nil {
+		check.Threshold = *conf.Threshold
+	}
+
+	return check
+}
+
+// toResources 将配置转换为 Resources
+func (conf *resourcesConfig) toResources() (*Resources, error) {
+	res := &Resources{Cgroup: conf.Cgroup}
+
+	if conf.MaxOpenFiles != nil {
+		res.MaxOpenFiles = *conf.MaxOpenFiles
+	}
+	if conf.MaxProcesses != nil {
+		res.MaxProcesses = *conf.MaxProcesses
+	}
+	if conf.CPUMax != nil {
+		res.CPUMax = *conf.CPUMax
+	}
+	if conf.SampleInterval != nil {
+		res.SampleInterval = time.Duration(*conf.SampleInterval) * time.Second
+	}
+
+	sizes := []struct {
+		name  string
+		value interface{}
+		set   func(size int64)
+	}{
+		{"max_address_space", conf.MaxAddressSpace, func(size int64) { res.MaxAddressSpace = uint64(size) }},
+		{"max_core_size", conf.MaxCoreSize, func(size int64) { res.MaxCoreSize = uint64(size) }},
+		{"memory_max", conf.MemoryMax, func(size int64) { res.MemoryMax = size }},
+		{"memory_threshold", conf.MemoryThreshold, func(size int64) { res.MemoryThreshold = size }},
+	}
+
+	for _, item := range sizes {
+		if item.value == nil {
+			continue
+		}
+
+		size, err := sizeValue(item.value)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", item.name, err)
+		}
+
+		item.set(size)
+	}
+
+	return res, nil
+}
+
+// parseExitCodes 解析逗号分隔的退出码列表：0,2
+func parseExitCodes(codes string) ([]int, error) {
+	result := make([]int, 0)
+	for _, code := range strings.Split(codes, ",") {
+		code = strings.TrimSpace(code)
+		if code == "" {
+			continue
+		}
+
+		val, err := strconv.Atoi(code)
+		if err != nil {
+			return nil, fmt.Errorf("invalid exit code %q", code)
+		}
+
+		result = append(result, val)
+	}
+
+	return result, nil
+}
+
+// sizeValue 解析配置中的文件大小，JSON 中的数字会被解析为 float64
+func sizeValue(val interface{}) (int64, error) {
+	if num, ok := val.(float64); ok {
+		if num != float64(int64(num)) {
+			return 0, fmt.Errorf("invalid size %v", val)
+		}
+
+		return parseSize(strconv.FormatInt(int64(num), 10))
+	}
+
+	return parseSize(fmt.Sprint(val))
+}
+
+// entryName 获取 JSON 配置项中的程序名称，用于错误提示
+func entryName(raw json.RawMessage, index int) string {
+	var entry struct {
+		Name string `json:"name"`
+	}
+
+	if err := json.Unmarshal(raw, &entry); err == nil && entry.Name != "" {
+		return entry.Name
+	}
+
+	return fmt.Sprintf("#%d", index)
+}
+
+// toProgram 将配置转换为 Program，使用与 supervisord 相同的默认值
+func (conf programConfig) toProgram() (*Program, error) {
+	program := NewProgram(conf.Name, conf.Command, conf.User, 1)
+	program.StartSecs = time.Second
+	program.StartRetries = 3
+	program.RestartPolicy = RestartOnFailure
+	program.BackoffInitial = time.Second
+	program.BackoffMax = time.Minute
+	program.Directory = conf.Directory
+
+	if conf.NumProcs != nil {
+		program.ProcNum = *conf.NumProcs
+	}
+	if conf.Environment != nil {
+		program.Environment = conf.Environment
+	}
+	if conf.AutoStart != nil {
+		program.AutoStart = *conf.AutoStart
+	}
+	if conf.StartSecs != nil {
+		program.StartSecs = time.Duration(*conf.StartSecs) * time.Second
+	}
+	if conf.StartRetries != nil {
+		program.StartRetries = *conf.StartRetries
+	}
+	if conf.StopWaitSecs != nil {
+		program.StopTimeout = time.Duration(*conf.StopWaitSecs) * time.Second
+	}
+	if conf.Priority != nil {
+		program.Priority = *conf.Priority
+	}
+	if conf.ExitCodes != nil {
+		program.ExitCodes = conf.ExitCodes
+	}
+	if conf.BackoffSecs != nil {
+		program.BackoffInitial = time.Duration(*conf.BackoffSecs) * time.Second
+	}
+	if conf.MaxBackoffSecs != nil {
+		program.BackoffMax = time.Duration(*conf.MaxBackoffSecs) * time.Second
+	}
+	if conf.MaxRestarts != nil {
+		program.MaxRestarts = *conf.MaxRestarts
+	}
+	if conf.ResetSecs != nil {
+		program.ResetWindow = time.Duration(*conf.ResetSecs) * time.Second
+	}
+	program.StdoutLogFile = conf.StdoutLogFile
+	program.StderrLogFile = conf.StderrLogFile
+	if conf.RedirectStderr != nil {
+		program.RedirectStderr = *conf.RedirectStderr
+	}
+	if conf.LogBackups != nil {
+		program.LogBackups = *conf.LogBackups
+	}
+	if conf.TailLines != nil {
+		program.TailLines = *conf.TailLines
+	}
+	if conf.HealthCheck != nil {
+		program.HealthCheck = conf.HealthCheck.toHealthCheck()
+	}
+	program.DependsOn = conf.DependsOn
+
+	errs := make(ConfigErrors, 0)
+	if conf.LogMaxBytes != nil {
+		size, err := sizeValue(conf.LogMaxBytes)
+		if err != nil {
+			errs = append(errs, &ConfigError{Program: conf.Name, Field: "logfile_maxbytes", Err: err})
+		} else {
+			program.LogMaxBytes = size
+		}
+	}
+
+	for _, spec := range conf.Sockets {
+		socket, err := ParseSocket(spec)
+		if err != nil {
+			errs = append(errs, &ConfigError{Program: conf.Name, Field: "sockets", Err: err})
+			continue
+		}
+
+		program.Sockets = append(program.Sockets, socket)
+	}
+
+	if conf.Resources != nil {
+		res, err := conf.Resources.toResources()
+		if err != nil {
+			errs = append(errs, &ConfigError{Program: conf.Name, Field: "resources", Err: err})
+		} else {
+			program.Resources = res
+		}
+	}
+
+	if conf.AutoRestart != nil {
+		policy, err := ParseRestartPolicy(fmt.Sprint(conf.AutoRestart))
+		if err != nil {
+			errs = append(errs, &ConfigError{Program: conf.Name, Field: "autorestart", Err: err})
+		} else {
+			program.RestartPolicy = policy
+		}
+	}
+
+	if conf.StopSignal != "" {
+		sig, err := parseSignal(conf.StopSignal)
+		if err != nil {
+			errs = append(errs, &ConfigError{Program: conf.Name, Field: "stopsignal", Err: err})
+		} else {
+			program.StopSignal = sig
+		}
+	}
+
+	if err := program.Validate(); err != nil {
+		errs = append(errs, err.(ConfigErrors)...)
+	}
+
+	if len(errs) > 0 {
+		if conf.Name == "" {
+			for _, err := range errs {
+				err.Program = "#" + strconv.Itoa(conf.index)
+			}
+		}
+
+		return nil, errs
+	}
+
+	return program, nil
+}
@@ -0,0 +1,144 @@
+package process
+
+import (
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestParseConfig(t *testing.T) {
+	configs := map[ConfigFormat]string{
+		FormatYAML: `
+programs:
+  - name: worker
+    command: /bin/sh -c "sleep 1; echo done"
+    numprocs: 2
+    environment:
+      APP_ENV: production
+    directory: /tmp
+    startsecs: 3
+    stopsignal: QUIT
+    priority: 10
+`,
+		FormatJSON: `{"programs": [{
+	"name": "worker",
+	"command": "/bin/sh -c \"sleep 1; echo done\"",
+	"numprocs": 2,
+	"environment": {"APP_ENV": "production"},
+	"directory": "/tmp",
+	"startsecs": 3,
+	"stopsignal": "QUIT",
+	"priority": 10
+}]}`,
+		FormatINI: `
+[program:worker]
+command=/bin/sh -c "sleep 1; echo done"
+numprocs=2
+environment=APP_ENV="production"
+directory=/tmp
+startsecs=3
+stopsignal=QUIT
+priority=10
+`,
+	}
+
+	for format, data := range configs {
+		config, err := ParseConfig([]byte(data), format)
+		if err != nil {
+			t.Fatalf("%s: parse failed: %s", format, err)
+		}
+
+		if len(config.Programs) != 1 {
+			t.Fatalf("%s: expect 1 program, got %d", format, len(config.Programs))
+		}
+
+		program := config.Programs[0]
+		if program.Name != "worker" || program.ProcNum != 2 || program.Directory != "/tmp" || program.Priority != 10 {
+			t.Errorf("%s: unexpected program %+v", format, program)
+		}
+
+		if program.Environment["APP_ENV"] != "production" {
+			t.Errorf("%s: unexpected environment %v", format, program.Environment)
+		}
+
+		if program.StartSecs != 3*time.Second || program.StartRetries != 3 || !program.AutoStart {
+			t.Errorf("%s: unexpected start options %+v", format, program)
+		}
+
+		if program.StopSignal != syscall.SIGQUIT {
+			t.Errorf("%s: expect stop signal QUIT, got %s", format, program.StopSignal)
+		}
+
+		command, args, _ := splitCommand(program.Command)
+		if command != "/bin/sh" || len(args) != 2 || args[1] != "sleep 1; echo done" {
+			t.Errorf("%s: unexpected command %s %q", format, command, args)
+		}
+	}
+}
+
+func TestParseConfigErrors(t *testing.T) {
+	testCases := []struct {
+		format ConfigFormat
+		data   string
+		expect []string
+	}{
+		{
+			format: FormatYAML,
+			data: `
+programs:
+  - name: worker
+    command: /bin/sleep 1
+    numprocs: 0
+  - name: worker2
+    command: /bin/sleep 1
+    stopsignal: NOPE
+`,
+			expect: []string{"program worker: numprocs: must be greater than 0", "program worker2: stopsignal"},
+		},
+		{
+			format: FormatJSON,
+			data:   `{"programs": [{"name": "worker", "command": "/bin/sleep 1", "numproc": 2}, {"command": "/bin/sleep 1"}]}`,
+			expect: []string{"program worker: json: unknown field \"numproc\"", "program #1: name: is required"},
+		},
+		{
+			format: FormatINI,
+			data:   "[program:worker]\ncommand=/bin/sleep \"1\nautostop=true\n\n[program:worker2]\ncommand=/bin/sleep 1\n\n[program:worker2]\ncommand=/bin/sleep 2\n",
+			expect: []string{"program worker: autostop: unknown option"},
+		},
+		{
+			format: FormatYAML,
+			data:   "programs:\n  - name: worker\n    command: /bin/sleep 1\n  - name: worker\n    command: /bin/sleep 2\n",
+			expect: []string{"program worker: name: duplicate program name"},
+		},
+	}
+
+	for _, tc := range testCases {
+		_, err := ParseConfig([]byte(tc.data), tc.format)
+		if err == nil {
+			t.Errorf("%s: expect error, got nil", tc.format)
+			continue
+		}
+
+		for _, expect := range tc.expect {
+			if !strings.Contains(err.Error(), expect) {
+				t.Errorf("%s: expect error contains %q, got %q", tc.format, expect, err)
+			}
+		}
+	}
+}
+
+func TestParseEnvironment(t *testing.T) {
+	env, err := parseEnvironment(`A="1,2",B=3, C='x=y'`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(env) != 3 || env["A"] != "1,2" || env["B"] != "3" || env["C"] != "x=y" {
+		t.Errorf("unexpected environment %v", env)
+	}
+
+	if _, err := parseEnvironment(`A="1`); err == nil {
+		t.Error("expect error for unterminated quote")
+	}
+}
@@ -0,0 +1,351 @@
+package process
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/mylxsw/asteria/log"
+)
+
+var (
+	// ErrProgramNotFound 程序不存在
+	ErrProgramNotFound = errors.New("program not found")
+	// ErrProcessNotFound 进程不存在
+	ErrProcessNotFound = errors.New("process not found")
+	// ErrNotWatching Manager 还没有开始运行（没有调用 Watch）或者已经停止
+	ErrNotWatching = errors.New("manager is not watching")
+)
+
+// StartProgram 启动程序的所有未运行的进程，已经进入 FATAL 状态的进程会重置重启计数后重新启动
+func (manager *Manager) StartProgram(name string) error {
+	manager.lock.Lock()
+	defer manager.lock.Unlock()
+
+	program, err := manager.getProgram(name)
+	if err != nil {
+		return err
+	}
+
+	if !manager.watching {
+		return ErrNotWatching
+	}
+
+	manager.startProgram(program)
+	return nil
+}
+
+// StopProgram 停止程序的所有进程并等待进程退出，停止后的进程不会被自动重启
+func (manager *Manager) StopProgram(name string) error {
+	manager.lock.Lock()
+	program, err := manager.getProgram(name)
+	var wait func()
+	if err == nil {
+		wait = manager.stopProgram(program)
+	}
+	manager.lock.Unlock()
+
+	if err != nil {
+		return err
+	}
+
+	wait()
+	return nil
+}
+
+// RestartProgram 重启程序的所有进程，正在运行的进程退出后立即重新启动，未运行的进程直接启动，
+// 等待所有旧的进程退出后返回
+func (manager *Manager) RestartProgram(name string) error {
+	manager.lock.Lock()
+	program, err := manager.getProgram(name)
+	if err == nil && !manager.watching {
+		err = ErrNotWatching
+	}
+	var wait func()
+	if err == nil {
+		program.active = true
+		wait = manager.restartProcesses(program.processes)
+	}
+	manager.lock.Unlock()
+
+	if err != nil {
+		return err
+	}
+
+	wait()
+	return nil
+}
+
+// Scale 调整程序的进程数量，多出的进程会被停止并移除，新增的进程在程序处于运行状态时自动启动
+func (manager *Manager) Scale(name string, procNum int) error {
+	if procNum < 1 {
+		return &ConfigError{Program: name, Field: "numprocs", Err: fmt.Errorf("must be greater than 0, got %d", procNum)}
+	}
+
+	manager.lock.Lock()
+	program, err := manager.getProgram(name)
+	var wait func()
+	if err == nil {
+		wait = manager.scale(program, procNum)
+	}
+	manager.lock.Unlock()
+
+	if err != nil {
+		return err
+	}
+
+	wait()
+	return nil
+}
+
+// RemoveProgram 停止并移除程序，等待进程退出后返回，其它程序依赖该程序时返回错误
+func (manager *Manager) RemoveProgram(name string) error {
+	manager.lock.Lock()
+	program, err := manager.getProgram(name)
+	if err == nil {
+		if dependents := manager.dependents(name); len(dependents) > 0 {
+			err = fmt.Errorf("program %s is required by %s", name, strings.Join(dependents, ", "))
+		}
+	}
+	var wait func()
+	if err == nil {
+		wait = manager.stopProgram(program)
+		delete(manager.programs, name)
+	}
+	manager.lock.Unlock()
+
+	if err != nil {
+		return err
+	}
+
+	wait()
+	program.release()
+	return nil
+}
+
+// Reload 使用新的配置替换当前配置，只有发生变化的程序会受到影响：
+// 新配置中不存在的程序被停止并移除，新增的程序被添加（AutoStart 时自动启动），
+// 只有进程数量发生变化的程序会调整进程数量，其它配置发生变化的程序会使用新配置重新创建，
+// 配置没有变化的程序保持不变。被替换的程序的进程全部退出后，才会启动新的程序
+func (manager *Manager) Reload(config *Config) error {
+	if errs := validatePrograms(config.Programs); len(errs) > 0 {
+		return errs
+	}
+
+	programs := make(map[string]*Program, len(config.Programs))
+	for _, program := range config.Programs {
+		programs[program.Name] = program
+	}
+
+	if errs := validateDependencies(programs); len(errs) > 0 {
+		return errs
+	}
+
+	manager.lock.Lock()
+
+	// 持有 manager.lock 时只请求停止进程，释放后再按照 stops 的顺序等待进程退出，
+	// 被移除的程序按照启动顺序的相反顺序停止
+	stops := make([]func(), 0)
+	current := manager.sortedPrograms()
+	for i := len(current) - 1; i >= 0; i-- {
+		program := current[i]
+		if _, ok := programs[program.Name]; !ok {
+			log.Debugf("program %s removed", program.Name)
+			wait := manager.stopProgram(program)
+			stops = append(stops, func() {
+				wait()
+				program.release()
+			})
+			delete(manager.programs, program.Name)
+		}
+	}
+
+	added := make([]*Program, 0)
+	for _, program := range sortPrograms(config.Programs) {
+		old, ok := manager.programs[program.Name]
+		if ok && old.sameConfig(program) {
+			if old.ProcNum != program.ProcNum {
+				log.Debugf("program %s scaled from %d to %d", program.Name, old.ProcNum, program.ProcNum)
+				stops = append(stops, manager.scale(old, program.ProcNum))
+			}
+			continue
+		}
+
+		if ok {
+			log.Debugf("program %s changed", program.Name)
+			wait := manager.stopProgram(old)
+			stops = append(stops, func() {
+				wait()
+				old.release()
+			})
+		} else {
+			log.Debugf("program %s added", program.Name)
+		}
+
+		manager.registerProgram(program)
+		added = append(added, program)
+	}
+	manager.lock.Unlock()
+
+	for _, stop := range stops {
+		stop()
+	}
+
+	// 旧的进程已经退出，监听的端口、socket 等资源已经释放，启动新增和被替换的程序
+	manager.lock.Lock()
+	defer manager.lock.Unlock()
+
+	for _, program := range added {
+		// 等待期间程序可能已经被再次替换或者移除
+		if manager.watching && program.AutoStart && manager.programs[program.Name] == program {
+			manager.startWhenReady(program)
+		}
+	}
+
+	return nil
+}
+
+// getProgram 获取程序，调用前需要持有 manager.lock
+func (manager *Manager) getProgram(name string) (*Program, error) {
+	program, ok := manager.programs[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrProgramNotFound, name)
+	}
+
+	return program, nil
+}
+
+// startProgram 启动程序的所有未运行的进程，调用前需要持有 manager.lock
+func (manager *Manager) startProgram(program *Program) {
+	program.active = true
+	for _, process := range program.processes {
+		manager.launch(process)
+	}
+}
+
+// stopProgram 请求停止程序的所有进程，返回结束进程并等待所有进程退出的函数，调用前需要持有 manager.lock，
+// 返回的函数需要在释放 manager.lock 之后调用，避免等待进程退出期间阻塞其它请求
+func (manager *Manager) stopProgram(program *Program) func() {
+	program.active = false
+	return manager.stopProcesses(program.processes)
+}
+
+// stopProcesses 请求停止多个进程，返回同时结束这些进程并等待所有进程退出的函数，调用前需要持有 manager.lock，
+// 返回的函数需要在释放 manager.lock 之后调用
+func (manager *Manager) stopProcesses(processes []*Process) func() {
+	processes = append([]*Process(nil), processes...)
+	exits := make([]chan struct{}, len(processes))
+	for i, process := range processes {
+		exits[i] = manager.stopProcess(process)
+	}
+
+	return func() {
+		manager.waitStop(processes, exits)
+	}
+}
+
+// restartProcesses 请求重启多个进程，返回等待所有旧的进程退出的函数，调用前需要持有 manager.lock，
+// 返回的函数需要在释放 manager.lock 之后调用
+func (manager *Manager) restartProcesses(processes []*Process) func() {
+	processes = append([]*Process(nil), processes...)
+	exits := make([]chan struct{}, len(processes))
+	for i, process := range processes {
+		exits[i] = manager.restart(process)
+	}
+
+	return func() {
+		manager.waitStop(processes, exits)
+	}
+}
+
+// waitStop 同时结束多个已经请求停止的进程，等待所有进程退出，exits 为 requestStop 返回的 channel
+func (manager *Manager) waitStop(processes []*Process, exits []chan struct{}) {
+	var wg sync.WaitGroup
+	for i, process := range processes {
+		wg.Add(1)
+		go func(process *Process, exited chan struct{}) {
+			defer wg.Done()
+			process.waitStop(exited, manager.closeTimeout)
+		}(process, exits[i])
+	}
+
+	wg.Wait()
+}
+
+// scale 调整程序的进程数量，返回等待被移除的进程退出的函数，调用前需要持有 manager.lock，
+// 返回的函数需要在释放 manager.lock 之后调用
+func (manager *Manager) scale(program *Program, procNum int) func() {
+	for i := len(program.processes); i < procNum; i++ {
+		process := program.newProcess(i, manager.processOutputFunc)
+		manager.initProcess(process)
+		program.processes = append(program.processes, process)
+
+		if manager.watching && program.active {
+			manager.launch(process)
+		}
+	}
+
+	wait := func() {}
+	if procNum < len(program.processes) {
+		wait = manager.stopProcesses(program.processes[procNum:])
+
+		program.processes = program.processes[:procNum]
+	}
+
+	program.ProcNum = procNum
+	return wait
+}
+
+// launch 启动没有在运行的进程
+func (manager *Manager) launch(process *Process) {
+	process.lock.Lock()
+	if process.running {
+		// 进程已经停止但还没有处理退出事件，处理退出事件时重新启动
+		if process.stopRequested {
+			process.restartRequested = true
+		}
+		process.lock.Unlock()
+		return
+	}
+
+	process.running = true
+	process.resetLocked()
+	process.lock.Unlock()
+
+	manager.startProcess(process, 0)
+}
+
+// stopProcess 请求停止进程，取消尚未完成的重启请求，返回进程退出时关闭的 channel
+func (manager *Manager) stopProcess(process *Process) chan struct{} {
+	process.lock.Lock()
+	process.restartRequested = false
+	process.lock.Unlock()
+
+	return process.requestStop()
+}
+
+// resetLocked 清除停止请求和重启计数，调用前需要持有 process.lock
+func (process *Process) resetLocked() {
+	process.stopRequested = false
+	process.restarts = 0
+	process.startFailures = 0
+}
+
+// stopped 进程退出后不再重启，更新进程状态
+func (process *Process) stopped(state State) {
+	process.lock.Lock()
+	defer process.lock.Unlock()
+
+	process.running = false
+	process.setStateLocked(state)
+}
+
+// restarted 进程退出后即将被重启，更新进程状态和重启次数
+func (process *Process) restarted(state State) {
+	process.lock.Lock()
+	defer process.lock.Unlock()
+
+	process.totalRestarts++
+	process.setStateLocked(state)
+}
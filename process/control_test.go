@@ -0,0 +1,123 @@
+package process
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func waitFor(t *testing.T, desc string, cond func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(3 * time.Second)
+	for time.Now().Before(deadline) {
+		if cond() {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	t.Fatalf("timeout waiting for %s", desc)
+}
+
+func TestManagerControl(t *testing.T) {
+	manager := NewManager(time.Second, nil)
+	if err := manager.AddPrograms(NewProgram("sleep", "/bin/sleep 10", "", 1)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := manager.StartProgram("sleep"); !errors.Is(err, ErrNotWatching) {
+		t.Errorf("expect ErrNotWatching, got %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		manager.Watch(ctx)
+		close(done)
+	}()
+
+	proc := manager.Programs()["sleep"].Processes()[0]
+	waitFor(t, "process started", func() bool { return proc.GetPID() > 0 })
+
+	if err := manager.StopProgram("sleep"); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "process stopped", func() bool { return !proc.IsRunning() })
+
+	if err := manager.StartProgram("sleep"); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "process started again", func() bool { return proc.GetPID() > 0 })
+
+	pid := proc.GetPID()
+	if err := manager.RestartProgram("sleep"); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "process restarted", func() bool { return proc.GetPID() > 0 && proc.GetPID() != pid })
+
+	// 停止后立即启动，进程的退出事件还没有被处理，处理完成后重新启动
+	pid = proc.GetPID()
+	if err := manager.StopProgram("sleep"); err != nil {
+		t.Fatal(err)
+	}
+	if err := manager.StartProgram("sleep"); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "process started after stop", func() bool { return proc.GetPID() > 0 && proc.GetPID() != pid })
+
+	if err := manager.Scale("sleep", 3); err != nil {
+		t.Fatal(err)
+	}
+	procs := manager.Programs()["sleep"].Processes()
+	waitFor(t, "process scaled up", func() bool {
+		return len(procs) == 3 && procs[1].GetPID() > 0 && procs[2].GetPID() > 0
+	})
+
+	if err := manager.Scale("sleep", 1); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "process scaled down", func() bool { return !procs[1].IsRunning() && !procs[2].IsRunning() })
+	if len(manager.Programs()["sleep"].Processes()) != 1 || !proc.IsRunning() {
+		t.Errorf("expect 1 running process after scaled down")
+	}
+
+	// sleep 只调整进程数量，echo 为新增程序
+	pid = proc.GetPID()
+	sleep := NewProgram("sleep", "/bin/sleep 10", "", 2)
+	echo := NewProgram("echo", "/bin/sleep 5", "", 1)
+	if err := manager.Reload(&Config{Programs: []*Program{sleep, echo}}); err != nil {
+		t.Fatal(err)
+	}
+
+	waitFor(t, "config reloaded", func() bool {
+		procs := manager.Programs()["sleep"].Processes()
+		return len(procs) == 2 && procs[1].GetPID() > 0 && echo.Processes()[0].GetPID() > 0
+	})
+	if proc.GetPID() != pid {
+		t.Errorf("unchanged process should not be restarted")
+	}
+
+	// sleep 的命令发生变化，需要重新创建
+	if err := manager.Reload(&Config{Programs: []*Program{NewProgram("sleep", "/bin/sleep 20", "", 1)}}); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "changed program recreated", func() bool {
+		return !proc.IsRunning() && !echo.Processes()[0].IsRunning() && manager.Programs()["sleep"].Processes()[0].GetPID() > 0
+	})
+
+	if err := manager.RemoveProgram("echo"); !errors.Is(err, ErrProgramNotFound) {
+		t.Errorf("expect ErrProgramNotFound, got %v", err)
+	}
+
+	if err := manager.RemoveProgram("sleep"); err != nil {
+		t.Fatal(err)
+	}
+	if len(manager.Programs()) != 0 {
+		t.Errorf("expect no programs after removed")
+	}
+
+	cancel()
+	<-done
+}
@@ -0,0 +1,212 @@
+package process
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mylxsw/asteria/log"
+)
+
+// dependencyCheckInterval 等待依赖的程序就绪时的检查间隔
+const dependencyCheckInterval = 100 * time.Millisecond
+
+// sortPrograms 按照依赖关系和启动优先级对程序排序：被依赖的程序排在前面，
+// 其它情况下优先级小的排在前面，优先级相同时按照名称排序，不在 programs 中的依赖会被忽略
+func sortPrograms(programs []*Program) []*Program {
+	pending := make([]*Program, len(programs))
+	copy(pending, programs)
+
+	sort.Slice(pending, func(i, j int) bool {
+		if pending[i].Priority != pending[j].Priority {
+			return pending[i].Priority < pending[j].Priority
+		}
+
+		return pending[i].Name < pending[j].Name
+	})
+
+	names := make(map[string]bool, len(pending))
+	for _, program := range pending {
+		names[program.Name] = true
+	}
+
+	sorted := make([]*Program, 0, len(pending))
+	placed := make(map[string]bool, len(pending))
+	for len(pending) > 0 {
+		// 选择第一个依赖已经全部排好的程序，存在循环依赖时直接选择第一个，避免死循环
+		next := 0
+		for i, program := range pending {
+			ready := true
+			for _, dep := range program.DependsOn {
+				if names[dep] && !placed[dep] {
+					ready = false
+					break
+				}
+			}
+
+			if ready {
+				next = i
+				break
+			}
+		}
+
+		sorted = append(sorted, pending[next])
+		placed[pending[next].Name] = true
+		pending = append(pending[:next], pending[next+1:]...)
+	}
+
+	return sorted
+}
+
+// validateDependencies 校验程序的依赖是否存在，以及是否存在循环依赖
+func validateDependencies(programs map[string]*Program) ConfigErrors {
+	errs := make(ConfigErrors, 0)
+
+	names := make([]string, 0, len(programs))
+	for name := range programs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		for _, dep := range programs[name].DependsOn {
+			if dep == name {
+				errs = append(errs, &ConfigError{Program: name, Field: "depends_on", Err: fmt.Errorf("program can not depend on itself")})
+			} else if _, ok := programs[dep]; !ok {
+				errs = append(errs, &ConfigError{Program: name, Field: "depends_on", Err: fmt.Errorf("unknown program %q", dep)})
+			}
+		}
+	}
+
+	const (
+		visiting = 1
+		visited  = 2
+	)
+
+	marks := make(map[string]int, len(programs))
+	var path []string
+	var visit func(name string)
+	visit = func(name string) {
+		marks[name] = visiting
+		path = append(path, name)
+
+		for _, dep := range programs[name].DependsOn {
+			if _, ok := programs[dep]; !ok || dep == name {
+				continue
+			}
+
+			switch marks[dep] {
+			case visiting:
+				start := 0
+				for i, n := range path {
+					if n == dep {
+						start = i
+					}
+				}
+
+				cycle := append(append([]string{}, path[start:]...), dep)
+				errs = append(errs, &ConfigError{Program: dep, Field: "depends_on", Err: fmt.Errorf("dependency cycle %s", strings.Join(cycle, " -> "))})
+			case 0:
+				visit(dep)
+			}
+		}
+
+		path = path[:len(path)-1]
+		marks[name] = visited
+	}
+
+	for _, name := range names {
+		if marks[name] == 0 {
+			visit(name)
+		}
+	}
+
+	return errs
+}
+
+// ready 程序的所有进程都处于 RUNNING 状态，并且配置了健康检查时健康检查已经通过
+func (program *Program) ready() bool {
+	for _, process := range program.processes {
+		if !process.ready() {
+			return false
+		}
+	}
+
+	return true
+}
+
+// ready 判断进程是否处于 RUNNING 状态，配置了健康检查时需要健康检查通过
+func (process *Process) ready() bool {
+	status := process.Status()
+	if status.State != StateRunning {
+		return false
+	}
+
+	return process.getProgram().HealthCheck == nil || status.Health == HealthHealthy
+}
+
+// dependenciesReady 判断程序依赖的程序是否都已经就绪，调用前需要持有 manager.lock
+func (manager *Manager) dependenciesReady(program *Program) bool {
+	for _, dep := range program.DependsOn {
+		if depProgram, ok := manager.programs[dep]; ok && !depProgram.ready() {
+			return false
+		}
+	}
+
+	return true
+}
+
+// dependents 获取依赖指定程序的所有程序名称，调用前需要持有 manager.lock
+func (manager *Manager) dependents(name string) []string {
+	dependents := make([]string, 0)
+	for _, program := range manager.programs {
+		for _, dep := range program.DependsOn {
+			if dep == name {
+				dependents = append(dependents, program.Name)
+				break
+			}
+		}
+	}
+
+	sort.Strings(dependents)
+	return dependents
+}
+
+// startWhenReady 依赖的程序都已经就绪时立即启动程序，否则等待依赖的程序就绪后再启动，调用前需要持有 manager.lock
+func (manager *Manager) startWhenReady(program *Program) {
+	if manager.dependenciesReady(program) {
+		manager.startProgram(program)
+		return
+	}
+
+	log.Debugf("program %s is waiting for dependencies %s", program.Name, strings.Join(program.DependsOn, ", "))
+
+	watchDone := manager.watchDone
+	go func() {
+		ticker := time.NewTicker(dependencyCheckInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-watchDone:
+				return
+			case <-ticker.C:
+			}
+
+			manager.lock.Lock()
+			// 程序已经被移除、替换或者 Manager 已经停止
+			if !manager.watching || manager.programs[program.Name] != program {
+				manager.lock.Unlock()
+				return
+			}
+
+			if manager.dependenciesReady(program) {
+				manager.startProgram(program)
+				manager.lock.Unlock()
+				return
+			}
+			manager.lock.Unlock()
+		}
+	}()
+}
@@ -0,0 +1,146 @@
+package process
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSortPrograms(t *testing.T) {
+	newProgram := func(name string, priority int, deps ...string) *Program {
+		program := NewProgram(name, "/bin/true", "", 1)
+		program.Priority = priority
+		program.DependsOn = deps
+		return program
+	}
+
+	sorted := sortPrograms([]*Program{
+		newProgram("worker", 1, "cache", "db"),
+		newProgram("cache", 100),
+		newProgram("db", 50),
+		newProgram("web", 10),
+		newProgram("cron", 10, "missing"),
+	})
+
+	names := make([]string, len(sorted))
+	for i, program := range sorted {
+		names[i] = program.Name
+	}
+
+	if expect := "cron,web,db,cache,worker"; strings.Join(names, ",") != expect {
+		t.Errorf("expect order %s, got %s", expect, strings.Join(names, ","))
+	}
+}
+
+func TestValidateDependencies(t *testing.T) {
+	_, err := ParseConfig([]byte(`
+programs:
+  - name: a
+    command: /bin/true
+    depends_on: [b]
+  - name: b
+    command: /bin/true
+    depends_on: [c]
+  - name: c
+    command: /bin/true
+    depends_on: [a]
+  - name: d
+    command: /bin/true
+    depends_on: [e]
+`), FormatYAML)
+	if err == nil {
+		t.Fatal("expect dependency errors")
+	}
+
+	for _, expect := range []string{"program d: depends_on: unknown program \"e\"", "dependency cycle a -> b -> c -> a"} {
+		if !strings.Contains(err.Error(), expect) {
+			t.Errorf("expect error contains %q, got %q", expect, err)
+		}
+	}
+
+	manager := NewManager(time.Second, nil)
+	cache := NewProgram("cache", "/bin/sleep 10", "", 1)
+	worker := NewProgram("worker", "/bin/sleep 10", "", 1)
+	worker.DependsOn = []string{"cache"}
+	if err := manager.AddPrograms(worker); err == nil {
+		t.Errorf("expect error for unknown dependency")
+	}
+
+	if err := manager.AddPrograms(cache, worker); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := manager.RemoveProgram("cache"); err == nil || !strings.Contains(err.Error(), "required by worker") {
+		t.Errorf("expect error when removing a required program, got %v", err)
+	}
+}
+
+func TestDependencyOrder(t *testing.T) {
+	cache := NewProgram("cache", "/bin/sleep 10", "", 2)
+	cache.StartSecs = 200 * time.Millisecond
+
+	worker := NewProgram("worker", "/bin/sleep 10", "", 1)
+	worker.StartSecs = 0
+	worker.DependsOn = []string{"cache"}
+
+	manager := NewManager(time.Second, nil)
+	if err := manager.AddPrograms(worker, cache); err != nil {
+		t.Fatal(err)
+	}
+
+	var lock sync.Mutex
+	events := make([]string, 0)
+	manager.SetStateChangeHandler(func(evt ProcessStateChangedEvent) {
+		lock.Lock()
+		defer lock.Unlock()
+
+		if evt.To == StateStarting || evt.To == StateRunning || evt.To == StateStopped {
+			events = append(events, evt.Program+":"+string(evt.To))
+		}
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		manager.Watch(ctx)
+		close(done)
+	}()
+
+	waitFor(t, "worker running", func() bool { return worker.Processes()[0].GetState() == StateRunning })
+
+	cancel()
+	<-done
+
+	waitFor(t, "all events dispatched", func() bool {
+		lock.Lock()
+		defer lock.Unlock()
+		return len(events) == 9
+	})
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	index := func(evt string, last bool) int {
+		pos := -1
+		for i, e := range events {
+			if e == evt {
+				pos = i
+				if !last {
+					break
+				}
+			}
+		}
+		return pos
+	}
+
+	// worker 在 cache 的所有进程都 RUNNING 之后启动，并且在 cache 停止之前停止
+	if index("worker:STARTING", false) < index("cache:RUNNING", true) {
+		t.Errorf("expect worker started after cache running, got %v", events)
+	}
+
+	if index("worker:STOPPED", true) > index("cache:STOPPED", false) {
+		t.Errorf("expect worker stopped before cache, got %v", events)
+	}
+}
@@ -0,0 +1,220 @@
+package process
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mylxsw/asteria/log"
+	"github.com/mylxsw/go-toolkit/executor"
+)
+
+// HealthCheckType 健康检查方式
+type HealthCheckType string
+
+const (
+	// HealthCheckHTTP 发送 HTTP GET 请求，响应状态码为 2xx 或者 3xx 时视为健康
+	HealthCheckHTTP HealthCheckType = "http"
+	// HealthCheckTCP 建立 TCP 连接，连接成功视为健康
+	HealthCheckTCP HealthCheckType = "tcp"
+	// HealthCheckExec 执行命令，命令执行成功（退出码为 0）视为健康
+	HealthCheckExec HealthCheckType = "exec"
+)
+
+const (
+	// DefaultHealthCheckInterval 默认的健康检查间隔
+	DefaultHealthCheckInterval = 10 * time.Second
+	// DefaultHealthCheckTimeout 默认的健康检查超时时间
+	DefaultHealthCheckTimeout = 3 * time.Second
+	// DefaultHealthCheckThreshold 默认的连续失败次数阈值
+	DefaultHealthCheckThreshold = 3
+	// DefaultStopTimeout 健康检查失败结束进程时，程序没有配置 StopTimeout 时等待进程退出的时间
+	DefaultStopTimeout = 10 * time.Second
+)
+
+// Health 进程的健康状态
+type Health string
+
+const (
+	// HealthUnknown 还没有完成健康检查
+	HealthUnknown Health = "UNKNOWN"
+	// HealthHealthy 最近一次健康检查成功
+	HealthHealthy Health = "HEALTHY"
+	// HealthUnhealthy 最近一次健康检查失败
+	HealthUnhealthy Health = "UNHEALTHY"
+)
+
+// HealthCheck 健康检查配置，进程进入 RUNNING 状态（持续运行 StartSecs）后立即检查一次，之后每隔 Interval 检查一次，
+// 连续失败 Threshold 次后重启进程（无论 RestartPolicy 如何配置）
+type HealthCheck struct {
+	Type HealthCheckType `json:"type"`
+	// Target 检查目标，http 为 URL，tcp 为 host:port，exec 为命令行，
+	// 其中的 {process_num} 会被替换为进程编号
+	Target string `json:"target"`
+	// Interval 检查间隔，为 0 时使用 DefaultHealthCheckInterval
+	Interval time.Duration `json:"interval"`
+	// Timeout 单次检查的超时时间，为 0 时使用 DefaultHealthCheckTimeout
+	Timeout time.Duration `json:"timeout"`
+	// Threshold 连续失败多少次后重启进程，为 0 时使用 DefaultHealthCheckThreshold
+	Threshold int `json:"threshold"`
+}
+
+// validate 校验健康检查配置
+func (check *HealthCheck) validate() error {
+	switch check.Type {
+	case HealthCheckHTTP, HealthCheckTCP:
+	case HealthCheckExec:
+		if _, _, err := splitCommand(check.Target); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("invalid type %q", check.Type)
+	}
+
+	if check.Target == "" {
+		return fmt.Errorf("target is required")
+	}
+
+	if check.Interval < 0 || check.Timeout < 0 || check.Threshold < 0 {
+		return fmt.Errorf("interval, timeout and threshold must not be negative")
+	}
+
+	return nil
+}
+
+func (check *HealthCheck) interval() time.Duration {
+	if check.Interval > 0 {
+		return check.Interval
+	}
+
+	return DefaultHealthCheckInterval
+}
+
+func (check *HealthCheck) timeout() time.Duration {
+	if check.Timeout > 0 {
+		return check.Timeout
+	}
+
+	return DefaultHealthCheckTimeout
+}
+
+func (check *HealthCheck) threshold() int {
+	if check.Threshold > 0 {
+		return check.Threshold
+	}
+
+	return DefaultHealthCheckThreshold
+}
+
+// run 执行一次健康检查
+func (check *HealthCheck) run(ctx context.Context, processNum int) error {
+	ctx, cancel := context.WithTimeout(ctx, check.timeout())
+	defer cancel()
+
+	target := strings.ReplaceAll(check.Target, "{process_num}", strconv.Itoa(processNum))
+
+	switch check.Type {
+	case HealthCheckHTTP:
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+		if err != nil {
+			return err
+		}
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode >= 400 {
+			return fmt.Errorf("unexpected status code %d", resp.StatusCode)
+		}
+	case HealthCheckTCP:
+		var dialer net.Dialer
+		conn, err := dialer.DialContext(ctx, "tcp", target)
+		if err != nil {
+			return err
+		}
+		conn.Close()
+	case HealthCheckExec:
+		command, args, _ := splitCommand(target)
+		if _, err := executor.New(command, args...).Run(ctx); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// watchHealth 定期检查进程的健康状态，连续失败次数达到阈值后结束进程，由 Manager 重新启动。
+// 进程持续运行 StartSecs（进入 RUNNING 状态）后立即检查一次，之后每隔 Interval 检查一次
+func (process *Process) watchHealth(ctx context.Context, check *HealthCheck) {
+	process.setHealth(HealthUnknown, 0, "")
+
+	delay := time.NewTimer(process.getProgram().StartSecs)
+	select {
+	case <-ctx.Done():
+		delay.Stop()
+		return
+	case <-delay.C:
+	}
+
+	ticker := time.NewTicker(check.interval())
+	defer ticker.Stop()
+
+	failures := 0
+	for {
+		err := check.run(ctx, process.index)
+		if ctx.Err() != nil {
+			return
+		}
+
+		if err == nil {
+			failures = 0
+			process.setHealth(HealthHealthy, 0, "")
+		} else {
+			failures++
+			process.setHealth(HealthUnhealthy, failures, err.Error())
+			log.Warningf("process %s health check failed (%d/%d): %s", process.GetName(), failures, check.threshold(), err)
+
+			if failures >= check.threshold() {
+				process.lock.Lock()
+				stopRequested := process.stopRequested
+				if !stopRequested {
+					process.forceRestart = true
+				}
+				pid, exited := process.pid, process.exited
+				process.lock.Unlock()
+
+				if !stopRequested {
+					log.Errorf("process %s is unhealthy, restarting", process.GetName())
+					process.terminate(pid, exited, DefaultStopTimeout)
+				}
+
+				return
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// setHealth 更新进程的健康状态
+func (process *Process) setHealth(health Health, failures int, lastError string) {
+	process.lock.Lock()
+	defer process.lock.Unlock()
+
+	process.health = health
+	process.healthFailures = failures
+	if lastError != "" {
+		process.healthError = lastError
+	}
+}
@@ -0,0 +1,142 @@
+package process
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestHealthCheckRun(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := listener.Addr().String()
+
+	tcp := &HealthCheck{Type: HealthCheckTCP, Target: addr}
+	if err := tcp.run(context.Background(), 0); err != nil {
+		t.Errorf("expect tcp check passed, got %s", err)
+	}
+
+	listener.Close()
+	if err := tcp.run(context.Background(), 0); err == nil {
+		t.Errorf("expect tcp check failed after listener closed")
+	}
+
+	exec := &HealthCheck{Type: HealthCheckExec, Target: "/bin/sh -c 'exit {process_num}'"}
+	if err := exec.run(context.Background(), 0); err != nil {
+		t.Errorf("expect exec check passed for process 0, got %s", err)
+	}
+	if err := exec.run(context.Background(), 1); err == nil {
+		t.Errorf("expect exec check failed for process 1")
+	}
+
+	slow := &HealthCheck{Type: HealthCheckExec, Target: "/bin/sleep 5", Timeout: 50 * time.Millisecond}
+	start := time.Now()
+	if err := slow.run(context.Background(), 0); err == nil || time.Since(start) > 2*time.Second {
+		t.Errorf("expect exec check timeout, got %v after %s", err, time.Since(start))
+	}
+}
+
+func TestHealthCheckRestart(t *testing.T) {
+	var healthy int32 = 1
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.LoadInt32(&healthy) == 0 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	program := NewProgram("web", "/bin/sleep 10", "", 1)
+	program.StartSecs = 0
+	program.RestartPolicy = RestartNever
+	program.BackoffInitial, program.BackoffMax = 10*time.Millisecond, 10*time.Millisecond
+	program.HealthCheck = &HealthCheck{Type: HealthCheckHTTP, Target: server.URL, Interval: 20 * time.Millisecond, Threshold: 2}
+
+	manager := NewManager(time.Second, nil)
+	if err := manager.AddPrograms(program); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		manager.Watch(ctx)
+		close(done)
+	}()
+
+	proc := program.Processes()[0]
+	waitFor(t, "process healthy", func() bool { return proc.Status().Health == HealthHealthy })
+
+	pid := proc.GetPID()
+	atomic.StoreInt32(&healthy, 0)
+
+	waitFor(t, "unhealthy process restarted", func() bool {
+		status := proc.Status()
+		return status.PID > 0 && status.PID != pid && status.Restarts == 1
+	})
+
+	if status := proc.Status(); status.HealthError == "" {
+		t.Errorf("expect health error recorded, got %+v", status)
+	}
+
+	atomic.StoreInt32(&healthy, 1)
+	waitFor(t, "process healthy again", func() bool { return proc.Status().Health == HealthHealthy })
+
+	cancel()
+	<-done
+}
+
+func TestParseHealthCheckConfig(t *testing.T) {
+	config, err := ParseConfig([]byte(`
+programs:
+  - name: web
+    command: /bin/sleep 10
+    healthcheck:
+      type: HTTP
+      target: http://127.0.0.1:8080/health
+      interval: 5
+      threshold: 2
+`), FormatYAML)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	check := config.Programs[0].HealthCheck
+	if check == nil || check.Type != HealthCheckHTTP || check.Interval != 5*time.Second || check.Threshold != 2 || check.timeout() != DefaultHealthCheckTimeout {
+		t.Errorf("unexpected health check %+v", check)
+	}
+
+	if _, err := ParseConfig([]byte("[program:web]\ncommand=/bin/sleep 10\nhealthcheck_type=udp\nhealthcheck_target=127.0.0.1:53\n"), FormatINI); err == nil {
+		t.Errorf("expect error for invalid health check type")
+	}
+}
+
+func TestHealthCheckFirstProbe(t *testing.T) {
+	program := NewProgram("web", "/bin/sleep 10", "", 1)
+	program.StartSecs = 0
+	program.HealthCheck = &HealthCheck{Type: HealthCheckExec, Target: "/bin/true", Interval: time.Hour}
+
+	manager := NewManager(time.Second, nil)
+	if err := manager.AddPrograms(program); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		manager.Watch(ctx)
+		close(done)
+	}()
+
+	// 第一次检查不需要等待一个完整的检查间隔
+	proc := program.Processes()[0]
+	waitFor(t, "process healthy", func() bool { return proc.Status().Health == HealthHealthy })
+
+	cancel()
+	<-done
+}
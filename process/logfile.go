@@ -0,0 +1,183 @@
+package process
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/mylxsw/asteria/log"
+)
+
+const (
+	// DefaultLogMaxBytes 日志文件的默认最大大小，超过后进行轮转
+	DefaultLogMaxBytes = 50 * 1024 * 1024
+	// DefaultLogBackups 默认保留的历史日志文件数量
+	DefaultLogBackups = 10
+)
+
+// rotateWriter 按照文件大小轮转的日志文件，轮转后的文件依次命名为 path.1、path.2 ...，
+// 编号越大越早，超过 backups 数量的文件会被删除
+type rotateWriter struct {
+	lock     sync.Mutex
+	path     string
+	maxBytes int64
+	backups  int
+
+	file *os.File
+	size int64
+}
+
+func newRotateWriter(path string, maxBytes int64, backups int) *rotateWriter {
+	return &rotateWriter{path: path, maxBytes: maxBytes, backups: backups}
+}
+
+// Write 写入日志，写入后文件大小超过 maxBytes 时先进行轮转，maxBytes 为 0 时不轮转
+func (writer *rotateWriter) Write(p []byte) (int, error) {
+	writer.lock.Lock()
+	defer writer.lock.Unlock()
+
+	if writer.file == nil {
+		if err := writer.open(); err != nil {
+			return 0, err
+		}
+	}
+
+	if writer.maxBytes > 0 && writer.size > 0 && writer.size+int64(len(p)) > writer.maxBytes {
+		if err := writer.rotate(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := writer.file.Write(p)
+	writer.size += int64(n)
+
+	return n, err
+}
+
+// Close 关闭日志文件，之后再写入时会重新打开
+func (writer *rotateWriter) Close() error {
+	writer.lock.Lock()
+	defer writer.lock.Unlock()
+
+	if writer.file == nil {
+		return nil
+	}
+
+	err := writer.file.Close()
+	writer.file = nil
+
+	return err
+}
+
+func (writer *rotateWriter) open() error {
+	if err := os.MkdirAll(filepath.Dir(writer.path), os.ModePerm); err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(writer.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+
+	stat, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	writer.file, writer.size = file, stat.Size()
+	return nil
+}
+
+// rotate 轮转日志文件：删除最早的文件，其它文件编号依次加一，当前文件重命名为 path.1
+func (writer *rotateWriter) rotate() error {
+	if err := writer.file.Close(); err != nil {
+		return err
+	}
+	writer.file = nil
+
+	if writer.backups <= 0 {
+		if err := os.Remove(writer.path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	} else {
+		_ = os.Remove(writer.backupPath(writer.backups))
+		for i := writer.backups - 1; i > 0; i-- {
+			if err := os.Rename(writer.backupPath(i), writer.backupPath(i+1)); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+		}
+
+		if err := os.Rename(writer.path, writer.backupPath(1)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	return writer.open()
+}
+
+func (writer *rotateWriter) backupPath(index int) string {
+	return writer.path + "." + strconv.Itoa(index)
+}
+
+// parseSize 解析文件大小，支持 KB、MB、GB 单位，不带单位时为字节数
+func parseSize(orig string) (int64, error) {
+	size := strings.ToUpper(strings.TrimSpace(orig))
+
+	unit := int64(1)
+	for suffix, val := range map[string]int64{"KB": 1 << 10, "MB": 1 << 20, "GB": 1 << 30} {
+		if strings.HasSuffix(size, suffix) {
+			unit = val
+			size = strings.TrimSpace(strings.TrimSuffix(size, suffix))
+			break
+		}
+	}
+
+	val, err := strconv.ParseInt(strings.TrimSuffix(size, "B"), 10, 64)
+	if err != nil || val < 0 {
+		return 0, fmt.Errorf("invalid size %q", orig)
+	}
+
+	return val * unit, nil
+}
+
+// initLogs 根据程序配置创建日志文件
+func (program *Program) initLogs() {
+	program.stdoutLog, program.stderrLog = nil, nil
+
+	if program.StdoutLogFile != "" {
+		program.stdoutLog = newRotateWriter(program.StdoutLogFile, program.LogMaxBytes, program.LogBackups)
+	}
+
+	if program.StderrLogFile != "" && !program.RedirectStderr {
+		program.stderrLog = newRotateWriter(program.StderrLogFile, program.LogMaxBytes, program.LogBackups)
+	}
+}
+
+// closeLogs 关闭程序的日志文件
+func (program *Program) closeLogs() {
+	for _, writer := range []*rotateWriter{program.stdoutLog, program.stderrLog} {
+		if writer != nil {
+			_ = writer.Close()
+		}
+	}
+}
+
+// writeLog 将进程输出写入对应的日志文件
+func (program *Program) writeLog(logType OutputType, line string) {
+	writer := program.stdoutLog
+	if logType == LogTypeStderr {
+		writer = program.stderrLog
+	}
+
+	if writer == nil {
+		return
+	}
+
+	if _, err := writer.Write([]byte(line + "\n")); err != nil {
+		log.Warningf("program %s write log failed: %s", program.Name, err)
+	}
+}
@@ -0,0 +1,103 @@
+package process
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRotateWriter(t *testing.T) {
+	dir, err := ioutil.TempDir("", "process_log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "logs", "test.log")
+	writer := newRotateWriter(path, 10, 2)
+	defer writer.Close()
+
+	for _, line := range []string{"line-1\n", "line-2\n", "line-3\n", "line-4\n"} {
+		if _, err := writer.Write([]byte(line)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for file, expect := range map[string]string{path: "line-4\n", path + ".1": "line-3\n", path + ".2": "line-2\n"} {
+		data, err := ioutil.ReadFile(file)
+		if err != nil || string(data) != expect {
+			t.Errorf("%s: expect %q, got %q (%v)", file, expect, data, err)
+		}
+	}
+
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Errorf("expect only 2 backups retained")
+	}
+}
+
+func TestParseSize(t *testing.T) {
+	for size, expect := range map[string]int64{"100": 100, "1KB": 1024, "50MB": 50 * 1024 * 1024, "1gb": 1 << 30, "0": 0} {
+		if val, err := parseSize(size); err != nil || val != expect {
+			t.Errorf("parseSize(%q): expect %d, got %d (%v)", size, expect, val, err)
+		}
+	}
+
+	if _, err := parseSize("10TB"); err == nil {
+		t.Errorf("expect error for invalid size")
+	}
+}
+
+func TestProgramLogs(t *testing.T) {
+	dir, err := ioutil.TempDir("", "process_log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	config, err := ParseConfig([]byte(`{"programs": [
+	{"name": "split", "command": "/bin/sh -c 'echo out; echo err >&2; sleep 10'", "stdout_logfile": "`+dir+`/split.out.log", "stderr_logfile": "`+dir+`/split.err.log", "logfile_maxbytes": 1048576},
+	{"name": "merged", "command": "/bin/sh -c 'echo out; echo err >&2; sleep 10'", "stdout_logfile": "`+dir+`/merged.log", "redirect_stderr": true, "tail_lines": 1}
+]}`), FormatJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if config.Programs[0].LogMaxBytes != 1048576 {
+		t.Errorf("expect logfile_maxbytes 1048576, got %d", config.Programs[0].LogMaxBytes)
+	}
+
+	manager := NewManager(time.Second, nil)
+	if err := manager.LoadConfig(config); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		manager.Watch(ctx)
+		close(done)
+	}()
+
+	readLog := func(name string) string {
+		data, _ := ioutil.ReadFile(filepath.Join(dir, name))
+		return string(data)
+	}
+
+	waitFor(t, "logs written", func() bool {
+		merged := readLog("merged.log")
+		return readLog("split.out.log") == "out\n" && readLog("split.err.log") == "err\n" &&
+			strings.Contains(merged, "out\n") && strings.Contains(merged, "err\n")
+	})
+
+	lines := config.Programs[1].Processes()[0].Tail(0)
+	if len(lines) != 1 || lines[0].Type != LogTypeStdout {
+		t.Errorf("expect 1 stdout line in tail buffer, got %+v", lines)
+	}
+
+	cancel()
+	<-done
+}
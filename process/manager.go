@@ -2,6 +2,8 @@ package process
 
 import (
 	"context"
+	"fmt"
+	"sync"
 	"time"
 
 	"github.com/mylxsw/asteria/log"
@@ -9,10 +11,16 @@ import (
 
 // Manager is process manager
 type Manager struct {
+	lock              sync.RWMutex
 	programs          map[string]*Program
 	restartProcess    chan *Process
+	watchDone         chan struct{}
 	closeTimeout      time.Duration
 	processOutputFunc OutputHandler
+	watching          bool
+	notifier          stateNotifier
+	pidDir            string
+	adoptPolicy       AdoptPolicy
 }
 
 // NewManager create a new process manager
@@ -26,33 +34,150 @@ func NewManager(closeTimeout time.Duration, processOutputFunc OutputHandler) *Ma
 
 // AddProgram add a new program to manager
 func (manager *Manager) AddProgram(name string, command string, procNum int, username string) {
-	manager.programs[name] = NewProgram(name, command, username, procNum).initProcesses(manager.processOutputFunc)
+	manager.lock.Lock()
+	defer manager.lock.Unlock()
+
+	manager.addProgram(NewProgram(name, command, username, procNum))
 }
 
-// Watch start watch process
-func (manager *Manager) Watch(ctx context.Context) {
+// AddPrograms 添加多个程序，程序配置不合法或者名称重复时返回错误，此时不会添加任何程序
+func (manager *Manager) AddPrograms(programs ...*Program) error {
+	manager.lock.Lock()
+	defer manager.lock.Unlock()
 
-	manager.restartProcess = make(chan *Process)
-	defer func() {
-		// close restartProcess channel to prevent goroutine leak
-		close(manager.restartProcess)
-	}()
+	errs := validatePrograms(programs)
+	for _, program := range programs {
+		if _, ok := manager.programs[program.Name]; ok {
+			errs = append(errs, &ConfigError{Program: program.Name, Field: "name", Err: fmt.Errorf("duplicate program name")})
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+
+	all := make(map[string]*Program, len(manager.programs)+len(programs))
+	for name, program := range manager.programs {
+		all[name] = program
+	}
+	for _, program := range programs {
+		all[program.Name] = program
+	}
+
+	if errs := validateDependencies(all); len(errs) > 0 {
+		return errs
+	}
+
+	for _, program := range sortPrograms(programs) {
+		manager.addProgram(program)
+	}
+
+	return nil
+}
+
+// LoadConfig 添加配置中的所有程序
+func (manager *Manager) LoadConfig(config *Config) error {
+	return manager.AddPrograms(config.Programs...)
+}
+
+// addProgram 添加程序，如果 Manager 已经在运行，自动启动 AutoStart 的程序，调用前需要持有 manager.lock
+func (manager *Manager) addProgram(program *Program) {
+	manager.registerProgram(program)
+
+	if manager.watching && program.AutoStart {
+		manager.startWhenReady(program)
+	}
+}
+
+// registerProgram 添加程序并创建程序的进程，不启动进程，调用前需要持有 manager.lock
+func (manager *Manager) registerProgram(program *Program) {
+	program.processes = make([]*Process, 0)
+	manager.programs[program.Name] = program.initProcesses(manager.processOutputFunc)
+	for _, process := range program.processes {
+		manager.initProcess(process)
+	}
+}
+
+// validatePrograms 校验程序配置，同时检查程序名称是否重复
+func validatePrograms(programs []*Program) ConfigErrors {
+	errs := make(ConfigErrors, 0)
+	names := make(map[string]bool)
+	for _, program := range programs {
+		if err := program.Validate(); err != nil {
+			errs = append(errs, err.(ConfigErrors)...)
+			continue
+		}
+
+		if names[program.Name] {
+			errs = append(errs, &ConfigError{Program: program.Name, Field: "name", Err: fmt.Errorf("duplicate program name")})
+			continue
+		}
+
+		names[program.Name] = true
+	}
+
+	return errs
+}
 
+// sortedPrograms 按照依赖关系和启动优先级返回所有程序
+func (manager *Manager) sortedPrograms() []*Program {
+	programs := make([]*Program, 0, len(manager.programs))
 	for _, program := range manager.programs {
-		for _, proc := range program.processes {
-			go manager.startProcess(proc, 0)
+		programs = append(programs, program)
+	}
+
+	return sortPrograms(programs)
+}
+
+// Watch start watch process, 按照依赖关系和启动优先级启动程序，程序依赖的程序全部就绪后才会启动，
+// ctx 结束后按照启动顺序的相反顺序停止所有进程，所有进程退出后才会返回
+func (manager *Manager) Watch(ctx context.Context) {
+	manager.lock.Lock()
+	manager.restartProcess = make(chan *Process)
+	manager.watchDone = make(chan struct{})
+	manager.watching = true
+	manager.recoverProcesses()
+	for _, program := range manager.sortedPrograms() {
+		if program.AutoStart {
+			manager.startWhenReady(program)
 		}
 	}
+	manager.lock.Unlock()
+
+	defer func() {
+		// close watchDone channel to prevent goroutine leak
+		close(manager.watchDone)
+	}()
 
 	for {
 		select {
 		case process := <-manager.restartProcess:
-			go manager.startProcess(process, process.retryDelayTime())
+			manager.handleExit(process)
 		case <-ctx.Done():
 			log.Debug("it's time to close all processes...")
-			for _, program := range manager.programs {
-				for _, proc := range program.processes {
-					proc.stop(manager.closeTimeout)
+
+			// 持有 manager.lock 时只请求停止进程，等待进程退出期间不阻塞状态查询等请求
+			manager.lock.Lock()
+			manager.watching = false
+			programs := manager.sortedPrograms()
+			processes := make([][]*Process, len(programs))
+			stops := make([]func(), len(programs))
+			for i, program := range programs {
+				processes[i] = append([]*Process(nil), program.processes...)
+				stops[i] = manager.stopProgram(program)
+			}
+			manager.lock.Unlock()
+
+			// 按照启动顺序的相反顺序结束进程
+			for i := len(programs) - 1; i >= 0; i-- {
+				stops[i]()
+				programs[i].release()
+
+				// Watch 返回后不再处理进程退出事件，这里直接更新进程状态
+				for _, process := range processes[i] {
+					if process.IsRunning() {
+						process.stopped(StateStopped)
+					}
 				}
 			}
 			return
@@ -60,11 +185,58 @@ func (manager *Manager) Watch(ctx context.Context) {
 	}
 }
 
+// handleExit 进程退出后，根据进程的状态和重启策略决定是否重启
+func (manager *Manager) handleExit(process *Process) {
+	manager.lock.Lock()
+	defer manager.lock.Unlock()
+
+	process.lock.Lock()
+	restartRequested, stopRequested := process.restartRequested, process.stopRequested
+	process.restartRequested = false
+	if restartRequested {
+		process.resetLocked()
+	}
+	process.lock.Unlock()
+
+	if restartRequested && manager.watching {
+		process.restarted(StateStopping)
+		manager.startProcess(process, 0)
+		return
+	}
+
+	if stopRequested || restartRequested {
+		process.stopped(StateStopped)
+		log.Debugf("process %s stopped", process.GetName())
+		return
+	}
+
+	delay, restart, reason := process.nextRestart()
+	if !restart {
+		if process.IsFatal() {
+			process.stopped(StateFatal)
+			log.Errorf("process %s entered fatal state: %s", process.GetName(), reason)
+		} else {
+			process.stopped(StateExited)
+			log.Warningf("process %s will not be restarted: %s", process.GetName(), reason)
+		}
+		return
+	}
+
+	if delay > 0 {
+		process.restarted(StateBackoff)
+	} else {
+		process.restarted(StateExited)
+	}
+	manager.startProcess(process, delay)
+}
+
 func (manager *Manager) startProcess(process *Process, delay time.Duration) {
 	if delay > 0 {
 		log.Debugf("process %s will start after %.2fs", process.GetName(), delay.Seconds())
 	}
 
+	restartProcess, watchDone := manager.restartProcess, manager.watchDone
+
 	process.lock.Lock()
 	defer process.lock.Unlock()
 
@@ -80,12 +252,23 @@ func (manager *Manager) startProcess(process *Process, delay time.Duration) {
 		log.Debugf("process %s starting...", process.GetName())
 		restartSignal := <-process.start()
 
-		manager.restartProcess <- restartSignal
+		select {
+		case restartProcess <- restartSignal:
+		case <-watchDone:
+		}
 	})
 
 }
 
 // Programs return all programs
 func (manager *Manager) Programs() map[string]*Program {
-	return manager.programs
+	manager.lock.RLock()
+	defer manager.lock.RUnlock()
+
+	programs := make(map[string]*Program, len(manager.programs))
+	for name, program := range manager.programs {
+		programs[name] = program
+	}
+
+	return programs
 }
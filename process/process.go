@@ -2,6 +2,7 @@ package process
 
 import (
 	"bufio"
+	"context"
 	"fmt"
 	"io"
 	"os"
@@ -25,6 +26,9 @@ const (
 	LogTypeStdout = OutputType("stdout")
 )
 
+// killTimeout 发送 SIGKILL 后等待进程退出的时间
+const killTimeout = 5 * time.Second
+
 // OutputHandler process output handler
 type OutputHandler func(logType OutputType, line string, process *Process)
 
@@ -44,6 +48,27 @@ type Process struct {
 	lock             sync.Mutex
 	outputHandler    OutputHandler
 	lastErrorMessage string // last error message
+	program          *Program
+	startFailures    int  // 连续启动失败次数
+	restarts         int  // 自上次稳定运行以来的连续重启次数
+	exitCode         int  // 最后一次退出时的退出码
+	running          bool // 进程是否正在运行或者等待启动
+	stopRequested    bool // 是否已经请求停止，请求停止后进程退出时不再重启
+	restartRequested bool // 是否已经请求重启，进程退出后立即重新启动
+	state            State
+	stateChangedAt   time.Time
+	startedAt        time.Time
+	totalRestarts    int // 进程被重启的总次数
+	stateListener    func(evt ProcessStateChangedEvent)
+	output           *tailBuffer   // 最近的输出
+	exited           chan struct{} // 本次运行的进程退出时关闭
+	index            int           // 进程在程序中的编号
+	health           Health
+	healthFailures   int    // 健康检查连续失败次数
+	healthError      string // 最近一次健康检查失败的原因
+	pidFile          string // 记录进程 pid 的文件，为空时不记录
+	forceRestart     bool   // 进程是否因为健康检查失败或者内存超限而被结束，退出后无论重启策略如何都需要重启
+	usage            ResourceUsage
 }
 
 // GetPID get process pid
@@ -110,6 +135,14 @@ func (process *Process) SetLastErrorMessage(msg string) {
 	process.lastErrorMessage = msg
 }
 
+// setExitCode update exit code
+func (process *Process) setExitCode(code int) {
+	process.lock.Lock()
+	defer process.lock.Unlock()
+
+	process.exitCode = code
+}
+
 // NewProcess create a new process
 func NewProcess(name string, command string, args []string, username string) *Process {
 	process := Process{
@@ -118,6 +151,10 @@ func NewProcess(name string, command string, args []string, username string) *Pr
 		args:    args,
 		user:    username,
 		stat:    make(chan *Process),
+
+		state:          StateStopped,
+		stateChangedAt: time.Now(),
+		output:         newTailBuffer(DefaultTailLines),
 	}
 
 	// need root privilege to set user or group, because setuid and setgid are privileged calls
@@ -145,48 +182,176 @@ func (process *Process) start() <-chan *Process {
 	go func() {
 		startTime := time.Now()
 
+		var exited chan struct{}
 		defer func() {
-			process.SetPID(0)
-			process.lastAliveTime = time.Now().Sub(startTime)
-
-			log.Warningf("process %s finished", process.name)
-			process.stat <- process
+			process.finish(startTime, exited)
 		}()
 
+		process.lock.Lock()
+		stopRequested := process.stopRequested
+		if !stopRequested {
+			exited = make(chan struct{})
+			process.exited = exited
+			process.resetHealthLocked()
+			process.setStateLocked(StateStarting)
+		}
+		process.lock.Unlock()
+
+		if stopRequested {
+			return
+		}
+
 		cmd := process.createCmd()
 
-		if process.outputHandler != nil {
-			stdoutPipe, _ := cmd.StdoutPipe()
-			stderrPipe, _ := cmd.StderrPipe()
+		if sockets := process.getProgram().Sockets; len(sockets) > 0 {
+			files, err := process.getProgram().sockets.open(sockets)
+			if err == nil {
+				err = passSockets(cmd, files, sockets)
+			}
+
+			if err != nil {
+				log.Errorf("process %s start failed: %s", process.name, err.Error())
+				process.SetLastErrorMessage(err.Error())
+				process.setExitCode(-1)
+				return
+			}
+		}
+
+		openGate, err := gateResources(cmd, process.getProgram().Resources)
+		if err != nil {
+			log.Errorf("process %s start failed: %s", process.name, err.Error())
+			process.SetLastErrorMessage(err.Error())
+			process.setExitCode(-1)
+			return
+		}
 
+		closeOutput := func() {}
+		if process.getPIDFile() != "" {
+			// 进程可能会在 Manager 退出后被接管，不能使用管道
+			if closeOutput, err = process.outputFiles(cmd); err != nil {
+				openGate()
+				log.Errorf("process %s start failed: %s", process.name, err.Error())
+				process.SetLastErrorMessage(err.Error())
+				process.setExitCode(-1)
+				return
+			}
+		} else {
+			stdoutPipe, _ := cmd.StdoutPipe()
 			go process.consoleLog(LogTypeStdout, &stdoutPipe)
-			go process.consoleLog(LogTypeStderr, &stderrPipe)
+
+			if process.getProgram().RedirectStderr {
+				cmd.Stderr = cmd.Stdout
+			} else {
+				stderrPipe, _ := cmd.StderrPipe()
+				go process.consoleLog(LogTypeStderr, &stderrPipe)
+			}
 		}
 
-		if err := cmd.Start(); err != nil {
+		err = cmd.Start()
+		closeOutput()
+		if err != nil {
+			openGate()
 			log.Errorf("process %s start failed: %s", process.name, err.Error())
 			process.SetLastErrorMessage(err.Error())
+			process.setExitCode(-1)
 			return
 		}
 
-		process.SetPID(cmd.Process.Pid)
+		// 进程启动过程中收到停止请求时，stop 无法获取到 pid，这里补发停止信号
+		process.lock.Lock()
+		process.pid = cmd.Process.Pid
+		process.startedAt = startTime
+		stopRequested = process.stopRequested
+		process.lock.Unlock()
+
+		// 设置资源限制后子进程才会执行真正的命令
+		cleanup := process.applyResources(cmd.Process.Pid)
+		defer cleanup()
+		openGate()
+
+		process.writePIDFile(cmd.Process.Pid)
+
+		if stopRequested {
+			_ = signalGroup(cmd.Process.Pid, process.getProgram().StopSignal)
+		} else {
+			process.markRunningAfter(cmd.Process.Pid, process.getProgram().StartSecs)
+
+			// 进程退出后结束健康检查和资源采样
+			watchCtx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			process.watch(watchCtx, cmd.Process.Pid)
+		}
 
 		if err := cmd.Wait(); err != nil {
 			log.Warningf("process %s stopped with error : %s", process.name, err.Error())
 			process.SetLastErrorMessage(err.Error())
 		}
+
+		process.setExitCode(cmd.ProcessState.ExitCode())
 	}()
 
 	return process.stat
 }
 
-// retryDelayTime get the next retry delay
-func (process *Process) retryDelayTime() time.Duration {
-	if process.lastAliveTime.Seconds() < 5 {
-		return 5 * time.Second
+// finish 进程退出（或者没有启动）后清理本次运行的状态，并通知 Manager 处理进程退出事件
+func (process *Process) finish(startTime time.Time, exited chan struct{}) {
+	process.lock.Lock()
+	process.pid = 0
+	process.exited = nil
+	process.usage = ResourceUsage{}
+	pidFile := process.pidFile
+	process.lock.Unlock()
+
+	if exited != nil && pidFile != "" {
+		if err := os.Remove(pidFile); err != nil && !os.IsNotExist(err) {
+			log.Warningf("remove pid file %s failed: %s", pidFile, err)
+		}
 	}
 
-	return 0
+	process.lastAliveTime = time.Now().Sub(startTime)
+	if exited != nil {
+		close(exited)
+	}
+
+	log.Warningf("process %s finished", process.name)
+	process.stat <- process
+}
+
+// watch 启动进程的健康检查和资源采样，ctx 结束后停止
+func (process *Process) watch(ctx context.Context, pid int) {
+	if check := process.getProgram().HealthCheck; check != nil {
+		go process.watchHealth(ctx, check)
+	}
+
+	go process.watchResources(ctx, pid)
+}
+
+// resetHealthLocked 清除上一次运行的健康状态，避免新进程在完成健康检查之前被认为是健康的，调用前需要持有 process.lock
+func (process *Process) resetHealthLocked() {
+	if process.getProgram().HealthCheck != nil {
+		process.health = HealthUnknown
+		process.healthFailures = 0
+	}
+}
+
+// markRunningAfter 进程持续运行 startSecs 后，状态从 STARTING 变为 RUNNING
+func (process *Process) markRunningAfter(pid int, startSecs time.Duration) {
+	markRunning := func() {
+		process.lock.Lock()
+		defer process.lock.Unlock()
+
+		if process.pid == pid && process.state == StateStarting {
+			process.setStateLocked(StateRunning)
+		}
+	}
+
+	if startSecs <= 0 {
+		markRunning()
+		return
+	}
+
+	time.AfterFunc(startSecs, markRunning)
 }
 
 // RemoveTimer remove the timer
@@ -200,40 +365,94 @@ func (process *Process) removeTimer() {
 	}
 }
 
-func (process *Process) stop(timeout time.Duration) {
-	process.removeTimer()
+// IsRunning 进程是否正在运行或者等待启动
+func (process *Process) IsRunning() bool {
+	process.lock.Lock()
+	defer process.lock.Unlock()
 
-	pid := process.GetPID()
-	name := process.GetName()
+	return process.running
+}
 
+// requestStop 请求停止进程：取消尚未开始的启动，并标记进程退出后不再重启，返回本次运行的进程退出时关闭的 channel，
+// 进程没有在运行时返回 nil。requestStop 不发送信号也不等待进程退出，可以在持有 manager.lock 时调用，
+// 之后需要在释放 manager.lock 后调用 waitStop 结束进程
+func (process *Process) requestStop() chan struct{} {
 	process.lock.Lock()
 	defer process.lock.Unlock()
 
-	if pid <= 0 {
-		log.Warningf("process %s with pid=%d is invalid", name, pid)
+	process.stopRequested = true
+	if process.timer != nil {
+		// 进程还在等待启动，取消启动即可
+		if process.timer.Stop() {
+			process.running = false
+			process.setStateLocked(StateStopped)
+		}
+		process.timer = nil
+	}
+	if process.running {
+		process.setStateLocked(StateStopping)
+	}
+
+	return process.exited
+}
+
+// waitStop 向 requestStop 返回的 exited 对应的进程组发送停止信号，并等待进程退出，
+// 超过 timeout 后进程仍未退出时，向整个进程组发送 SIGKILL 信号。等待时间较长，调用时不能持有 manager.lock
+func (process *Process) waitStop(exited chan struct{}, timeout time.Duration) {
+	if exited == nil {
+		log.Debugf("process %s is not running", process.GetName())
 		return
 	}
 
-	proc, err := os.FindProcess(pid)
-	if err != nil {
-		log.Warningf("process %s with pid=%d doesn't exist", name, pid)
+	process.lock.Lock()
+	pid, current := process.pid, process.exited == exited
+	process.lock.Unlock()
+
+	// 进程已经退出（可能已经被重新启动），不能向新的进程发送信号
+	if !current {
+		<-exited
 		return
 	}
 
-	stopped := make(chan interface{})
-	go func() {
-		proc.Signal(syscall.SIGTERM)
-		close(stopped)
+	process.terminate(pid, exited, timeout)
+}
 
-		log.Debugf("process %s gracefully stopped", name)
-	}()
+// terminate 向进程组发送停止信号，等待进程退出，超过 timeout 后进程仍未退出时，向整个进程组发送 SIGKILL 信号，
+// 程序配置了 StopTimeout 时使用 StopTimeout 作为超时时间
+func (process *Process) terminate(pid int, exited chan struct{}, timeout time.Duration) {
+	program := process.getProgram()
+	if program.StopTimeout > 0 {
+		timeout = program.StopTimeout
+	}
+
+	name := process.GetName()
+
+	// pid 为 0 时进程正在启动，启动完成后会自行发送停止信号
+	if pid > 0 {
+		if err := signalGroup(pid, program.StopSignal); err != nil {
+			log.Warningf("send signal %s to process %s failed: %s", program.StopSignal, name, err)
+			timeout = 0
+		}
+	}
 
 	select {
-	case <-stopped:
+	case <-exited:
+		log.Debugf("process %s gracefully stopped", name)
 		return
 	case <-time.After(timeout):
-		proc.Signal(syscall.SIGKILL)
+	}
+
+	if pid := process.GetPID(); pid > 0 {
+		if err := signalGroup(pid, syscall.SIGKILL); err != nil {
+			log.Warningf("kill process %s failed: %s", name, err)
+		}
+	}
+
+	select {
+	case <-exited:
 		log.Debugf("process %s forced stopped", name)
+	case <-time.After(killTimeout):
+		log.Errorf("process %s is still alive after killed", name)
 	}
 }
 
@@ -248,8 +467,12 @@ func (process *Process) consoleLog(logType OutputType, input *io.ReadCloser) err
 			break
 		}
 
+		line = strings.Trim(line, "\n")
+		process.output.write(OutputLine{Time: time.Now(), Process: process.GetName(), Type: logType, Line: line})
+		process.getProgram().writeLog(logType, line)
+
 		if process.outputHandler != nil {
-			process.outputHandler(logType, strings.Trim(line, "\n"), process)
+			process.outputHandler(logType, line, process)
 		}
 	}
 
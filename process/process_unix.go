@@ -1,24 +1,39 @@
+//go:build !windows
 // +build !windows
 
 package process
 
 import (
+	"fmt"
+	"net"
+	"os"
 	"os/exec"
 	"strconv"
+	"strings"
+	"sync"
 	"syscall"
 )
 
 func (process *Process) createCmd() *exec.Cmd {
 	cmd := exec.Command(process.GetCommand(), process.GetArgs()...)
-	// 这里就不重新创建进程组了，创建新的进程组，在当前程序意外退出后，启动的外部进程时无法自动关闭的
-	// cmd.SysProcAttr = &syscall.SysProcAttr{
-	// 	Setpgid: true,
-	// }
+	// 创建新的进程组，停止进程时向整个进程组发送信号，避免进程创建的子进程成为孤儿进程，
+	// 注意在新的进程组中，当前程序意外退出后，启动的外部进程不会自动关闭
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
 
 	if process.uid != "" {
 		cmd.SysProcAttr.Credential = createCredential(process.uid)
 	}
 
+	if program := process.program; program != nil {
+		cmd.Dir = program.Directory
+		if len(program.Environment) > 0 {
+			cmd.Env = os.Environ()
+			for key, value := range program.Environment {
+				cmd.Env = append(cmd.Env, key+"="+value)
+			}
+		}
+	}
+
 	return cmd
 }
 
@@ -31,3 +46,101 @@ func createCredential(uid string) *syscall.Credential {
 
 	return &credential
 }
+
+// listenPIDScript 将 LISTEN_PID 设置为 shell 自身的 pid 后执行真正的命令，exec 不会改变 pid，
+// 因此 LISTEN_PID 与最终执行的程序的 pid 相同
+const listenPIDScript = `LISTEN_PID=$$; export LISTEN_PID; exec "$0" "$@"`
+
+// passSockets 将监听 socket 作为文件描述符 3、4... 传递给子进程，并设置 LISTEN_* 环境变量
+func passSockets(cmd *exec.Cmd, files []*os.File, sockets []*Socket) error {
+	if cmd.Err != nil {
+		return cmd.Err
+	}
+
+	cmd.ExtraFiles = files
+	if cmd.Env == nil {
+		cmd.Env = os.Environ()
+	}
+	cmd.Env = append(withoutListenEnv(cmd.Env), listenEnv(sockets)...)
+
+	cmd.Args = append([]string{"/bin/sh", "-c", listenPIDScript, cmd.Path}, cmd.Args[1:]...)
+	cmd.Path = "/bin/sh"
+
+	return nil
+}
+
+// resourceGateScript 子进程先阻塞在读取管道上，Manager 为其设置 rlimit 并放置到 cgroup 后关闭管道写端，
+// 子进程关闭管道后再执行真正的命令。exec 不改变 pid，rlimit 和 cgroup 都会被保留，
+// 因此真正的命令从第一条指令开始就受到限制，它创建的子进程也都在 cgroup 中
+const resourceGateScript = `read gate <&%d; exec %d<&-; exec "$0" "$@"`
+
+// gateResources 程序配置了 rlimit 或者 cgroup 时，使用 resourceGateScript 包装命令，
+// 返回的函数用于在设置完资源限制后放行子进程（进程启动失败时同样需要调用，用于关闭管道）
+func gateResources(cmd *exec.Cmd, res *Resources) (func(), error) {
+	if res == nil || !res.limited() {
+		return func() {}, nil
+	}
+
+	if cmd.Err != nil {
+		return nil, cmd.Err
+	}
+
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+
+	fd := listenFDsStart + len(cmd.ExtraFiles)
+	cmd.ExtraFiles = append(cmd.ExtraFiles, reader)
+	cmd.Args = append([]string{"/bin/sh", "-c", fmt.Sprintf(resourceGateScript, fd, fd), cmd.Path}, cmd.Args[1:]...)
+	cmd.Path = "/bin/sh"
+
+	return func() {
+		_ = writer.Close()
+		_ = reader.Close()
+	}, nil
+}
+
+// umaskLock 保证同一时间只有一个 listenPrivate 修改 umask
+var umaskLock sync.Mutex
+
+// listenPrivate 创建只允许当前用户访问（0600）的 Unix Socket，创建时临时将 umask 设置为 0177，
+// socket 文件从创建开始就是受限的权限。umask 是进程级别的设置，创建期间其它 goroutine 新建的文件同样会受到影响
+func listenPrivate(path string) (net.Listener, error) {
+	umaskLock.Lock()
+	defer umaskLock.Unlock()
+
+	mask := syscall.Umask(0177)
+	defer syscall.Umask(mask)
+
+	return net.Listen("unix", path)
+}
+
+// signalGroup 向进程所在的进程组发送信号
+func signalGroup(pid int, sig syscall.Signal) error {
+	return syscall.Kill(-pid, sig)
+}
+
+var signals = map[string]syscall.Signal{
+	"HUP":  syscall.SIGHUP,
+	"INT":  syscall.SIGINT,
+	"QUIT": syscall.SIGQUIT,
+	"KILL": syscall.SIGKILL,
+	"TERM": syscall.SIGTERM,
+	"USR1": syscall.SIGUSR1,
+	"USR2": syscall.SIGUSR2,
+}
+
+// parseSignal 解析信号名称，支持 TERM、SIGTERM 或者信号编号
+func parseSignal(name string) (syscall.Signal, error) {
+	name = strings.TrimPrefix(strings.ToUpper(strings.TrimSpace(name)), "SIG")
+	if sig, ok := signals[name]; ok {
+		return sig, nil
+	}
+
+	if num, err := strconv.Atoi(name); err == nil && num > 0 {
+		return syscall.Signal(num), nil
+	}
+
+	return 0, fmt.Errorf("unsupported signal %q", name)
+}
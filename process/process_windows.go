@@ -1,12 +1,70 @@
+//go:build windows
 // +build windows
 
 package process
 
 import (
+	"fmt"
+	"net"
+	"os"
 	"os/exec"
+	"strings"
+	"syscall"
 )
 
 func (process *Process) createCmd() *exec.Cmd {
 	cmd := exec.Command(process.GetCommand(), process.GetArgs()...)
+
+	if program := process.program; program != nil {
+		cmd.Dir = program.Directory
+		if len(program.Environment) > 0 {
+			cmd.Env = os.Environ()
+			for key, value := range program.Environment {
+				cmd.Env = append(cmd.Env, key+"="+value)
+			}
+		}
+	}
+
 	return cmd
 }
+
+// passSockets windows 不支持向子进程传递文件描述符
+func passSockets(cmd *exec.Cmd, files []*os.File, sockets []*Socket) error {
+	return fmt.Errorf("socket activation is not supported on windows")
+}
+
+// gateResources windows 不支持资源限制，不需要包装命令
+func gateResources(cmd *exec.Cmd, res *Resources) (func(), error) {
+	return func() {}, nil
+}
+
+// listenPrivate windows 没有 umask，直接创建 Unix Socket，访问权限由所在目录的 ACL 控制
+func listenPrivate(path string) (net.Listener, error) {
+	return net.Listen("unix", path)
+}
+
+// signalGroup windows 不支持进程组和 KILL 以外的信号，只能结束进程本身
+func signalGroup(pid int, sig syscall.Signal) error {
+	if sig != syscall.SIGKILL {
+		return fmt.Errorf("signal %s is not supported on windows", sig)
+	}
+
+	proc, err := os.FindProcess(pid)
+	if err != nil {
+		return err
+	}
+
+	return proc.Kill()
+}
+
+// parseSignal windows 只支持 TERM 和 KILL 信号
+func parseSignal(name string) (syscall.Signal, error) {
+	switch strings.TrimPrefix(strings.ToUpper(strings.TrimSpace(name)), "SIG") {
+	case "TERM":
+		return syscall.SIGTERM, nil
+	case "KILL":
+		return syscall.SIGKILL, nil
+	}
+
+	return 0, fmt.Errorf("unsupported signal %q", name)
+}
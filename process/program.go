@@ -2,46 +2,339 @@ package process
 
 import (
 	"fmt"
+	"os/user"
+	"reflect"
 	"strings"
+	"syscall"
+	"time"
+)
+
+const (
+	// RetryForever 启动失败后无限次重试
+	RetryForever = -1
+	// DefaultStartSecs 进程启动后，需要持续运行多长时间才被认为启动成功
+	DefaultStartSecs = 5 * time.Second
+	// DefaultPriority 默认的启动优先级
+	DefaultPriority = 999
+	// DefaultBackoff 进程未能稳定运行时，重启前的默认等待时间
+	DefaultBackoff = 5 * time.Second
 )
 
 // Program is the program we want to execute
 type Program struct {
-	Name      string `json:"name,omitempty"`
-	Command   string `json:"command,omitempty"`
-	User      string `json:"user,omitempty"`
-	ProcNum   int    `json:"proc_num,omitempty"`
+	Name    string `json:"name,omitempty"`
+	Command string `json:"command,omitempty"`
+	User    string `json:"user,omitempty"`
+	ProcNum int    `json:"proc_num,omitempty"`
+	// Environment 进程的额外环境变量，会追加到当前进程的环境变量之后
+	Environment map[string]string `json:"environment,omitempty"`
+	// Directory 进程的工作目录，为空时使用当前进程的工作目录
+	Directory string `json:"directory,omitempty"`
+	// AutoStart 是否在 Manager.Watch 时自动启动
+	AutoStart bool `json:"auto_start"`
+	// StartSecs 进程启动后需要持续运行的时间，运行时间小于该值时视为启动失败
+	StartSecs time.Duration `json:"start_secs"`
+	// StartRetries 连续启动失败的最大重试次数，超过后不再重启，RetryForever 表示无限重试
+	StartRetries int `json:"start_retries"`
+	// StopSignal 停止进程时发送的信号，默认为 SIGTERM
+	StopSignal syscall.Signal `json:"stop_signal"`
+	// StopTimeout 发送停止信号后等待进程退出的时间，超时后强制结束进程，为 0 时使用 Manager 的 closeTimeout
+	StopTimeout time.Duration `json:"stop_timeout"`
+	// Priority 启动优先级，值越小越先启动，越晚停止
+	Priority int `json:"priority"`
+	// RestartPolicy 进程退出后的重启策略，为空时等同于 RestartAlways
+	RestartPolicy RestartPolicy `json:"restart_policy"`
+	// ExitCodes 预期的退出码，RestartOnFailure 策略下以这些退出码退出时不重启
+	ExitCodes []int `json:"exit_codes"`
+	// BackoffInitial 进程未能稳定运行时，第一次重启前的等待时间，之后每次重启等待时间翻倍
+	BackoffInitial time.Duration `json:"backoff_initial"`
+	// BackoffMax 重启等待时间的上限
+	BackoffMax time.Duration `json:"backoff_max"`
+	// MaxRestarts 未能稳定运行时的最大连续重启次数，超过后进程进入 FATAL 状态，RetryForever 表示不限制
+	MaxRestarts int `json:"max_restarts"`
+	// ResetWindow 进程持续运行超过该时间视为稳定运行，重启计数和等待时间清零，为 0 时使用 StartSecs
+	ResetWindow time.Duration `json:"reset_window"`
+
+	// StdoutLogFile 标准输出日志文件，为空时不记录
+	StdoutLogFile string `json:"stdout_logfile,omitempty"`
+	// StderrLogFile 标准错误输出日志文件，为空时不记录
+	StderrLogFile string `json:"stderr_logfile,omitempty"`
+	// RedirectStderr 将标准错误输出合并到标准输出
+	RedirectStderr bool `json:"redirect_stderr"`
+	// LogMaxBytes 日志文件的最大大小，超过后进行轮转，为 0 时不轮转
+	LogMaxBytes int64 `json:"log_max_bytes"`
+	// LogBackups 轮转时保留的历史日志文件数量
+	LogBackups int `json:"log_backups"`
+	// TailLines 每个进程在内存中保留的最近输出行数，为 0 时不保留
+	TailLines int `json:"tail_lines"`
+	// DependsOn 依赖的程序，依赖的程序全部处于 RUNNING 状态（配置了健康检查时需要健康检查通过）后才会自动启动，
+	// 停止时先于依赖的程序停止
+	DependsOn []string `json:"depends_on,omitempty"`
+	// HealthCheck 健康检查配置，为 nil 时不进行健康检查
+	HealthCheck *HealthCheck `json:"health_check,omitempty"`
+	// Sockets 由 Manager 创建并传递给子进程的监听 socket，进程重启期间保持打开
+	Sockets []*Socket `json:"sockets,omitempty"`
+	// Resources 资源限制配置，为 nil 时不限制，进程的资源使用情况始终会被采样
+	Resources *Resources `json:"resources,omitempty"`
+
 	processes []*Process
+	active    bool // 程序是否应该处于运行状态
+	stdoutLog *rotateWriter
+	stderrLog *rotateWriter
+	sockets   *socketSet
 }
 
 // NewProgram create a new Program
 func NewProgram(name, command, username string, procNum int) *Program {
 	return &Program{
-		Name:      name,
-		Command:   command,
-		User:      username,
-		ProcNum:   procNum,
-		processes: make([]*Process, 0),
+		Name:           name,
+		Command:        command,
+		User:           username,
+		ProcNum:        procNum,
+		Environment:    make(map[string]string),
+		AutoStart:      true,
+		StartSecs:      DefaultStartSecs,
+		StartRetries:   RetryForever,
+		StopSignal:     syscall.SIGTERM,
+		Priority:       DefaultPriority,
+		RestartPolicy:  RestartAlways,
+		ExitCodes:      []int{0},
+		BackoffInitial: DefaultBackoff,
+		BackoffMax:     DefaultBackoff,
+		MaxRestarts:    RetryForever,
+		LogMaxBytes:    DefaultLogMaxBytes,
+		LogBackups:     DefaultLogBackups,
+		TailLines:      DefaultTailLines,
+		processes:      make([]*Process, 0),
 	}
 }
 
-func (program *Program) initProcesses(outputFunc OutputHandler) *Program {
-	snips := strings.Split(program.Command, " ")
-	command, args := snips[0], snips[1:]
+// Validate 校验程序配置是否合法，返回的错误为 ConfigErrors
+func (program *Program) Validate() error {
+	name := program.Name
+	if name == "" {
+		name = "<unnamed>"
+	}
+
+	errs := make(ConfigErrors, 0)
+	addErr := func(field string, format string, args ...interface{}) {
+		errs = append(errs, &ConfigError{Program: name, Field: field, Err: fmt.Errorf(format, args...)})
+	}
+
+	if program.Name == "" {
+		addErr("name", "is required")
+	} else if strings.ContainsAny(program.Name, "/ \t") {
+		addErr("name", "must not contain '/' or whitespace")
+	}
+
+	if command, _, err := splitCommand(program.Command); err != nil {
+		addErr("command", "%s", err)
+	} else if command == "" {
+		addErr("command", "is required")
+	}
+
+	if program.ProcNum < 1 {
+		addErr("numprocs", "must be greater than 0, got %d", program.ProcNum)
+	}
+
+	if program.User != "" {
+		if _, err := user.Lookup(program.User); err != nil {
+			addErr("user", "%s", err)
+		}
+	}
+
+	if program.StartSecs < 0 {
+		addErr("startsecs", "must not be negative")
+	}
+
+	if program.StartRetries < RetryForever {
+		addErr("startretries", "must not be negative")
+	}
 
+	if program.StopTimeout < 0 {
+		addErr("stopwaitsecs", "must not be negative")
+	}
+
+	switch program.RestartPolicy {
+	case "", RestartAlways, RestartOnFailure, RestartNever:
+	default:
+		addErr("autorestart", "invalid restart policy %q", program.RestartPolicy)
+	}
+
+	if program.BackoffInitial < 0 {
+		addErr("backoffsecs", "must not be negative")
+	}
+
+	if program.BackoffMax < program.BackoffInitial {
+		addErr("maxbackoffsecs", "must not be less than backoffsecs")
+	}
+
+	if program.MaxRestarts < RetryForever {
+		addErr("maxrestarts", "must not be negative")
+	}
+
+	if program.ResetWindow < 0 {
+		addErr("resetsecs", "must not be negative")
+	}
+
+	if program.LogMaxBytes < 0 {
+		addErr("logfile_maxbytes", "must not be negative")
+	}
+
+	if program.LogBackups < 0 {
+		addErr("logfile_backups", "must not be negative")
+	}
+
+	if program.TailLines < 0 {
+		addErr("tail_lines", "must not be negative")
+	}
+
+	for _, dep := range program.DependsOn {
+		if dep == "" {
+			addErr("depends_on", "program name must not be empty")
+		}
+	}
+
+	if program.HealthCheck != nil {
+		if err := program.HealthCheck.validate(); err != nil {
+			addErr("healthcheck", "%s", err)
+		}
+	}
+
+	addresses := make(map[string]bool)
+	for _, socket := range program.Sockets {
+		if err := socket.validate(); err != nil {
+			addErr("sockets", "%s", err)
+		} else if addresses[socket.Network+"://"+socket.Address] {
+			addErr("sockets", "duplicate socket %s", socket)
+		}
+		addresses[socket.Network+"://"+socket.Address] = true
+	}
+
+	if program.Resources != nil {
+		if err := program.Resources.validate(); err != nil {
+			addErr("resources", "%s", err)
+		}
+	}
+
+	for key := range program.Environment {
+		if key == "" || strings.Contains(key, "=") {
+			addErr("environment", "invalid variable name %q", key)
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+
+	return nil
+}
+
+func (program *Program) initProcesses(outputFunc OutputHandler) *Program {
+	program.initLogs()
+	program.sockets = &socketSet{}
 	for i := 0; i < program.ProcNum; i++ {
-		program.processes = append(program.processes, NewProcess(
-			fmt.Sprintf("%s/%d", program.Name, i),
-			command,
-			args,
-			program.User,
-		).setOutputFunc(outputFunc))
+		program.processes = append(program.processes, program.newProcess(i, outputFunc))
 	}
 
 	return program
 }
 
+// newProcess 创建程序的第 index 个进程
+func (program *Program) newProcess(index int, outputFunc OutputHandler) *Process {
+	command, args, _ := splitCommand(program.Command)
+
+	process := NewProcess(
+		fmt.Sprintf("%s/%d", program.Name, index),
+		command,
+		args,
+		program.User,
+	).setOutputFunc(outputFunc)
+	process.program = program
+	process.index = index
+	process.output = newTailBuffer(program.TailLines)
+
+	return process
+}
+
+// sameConfig 判断两个程序除了进程数量之外的配置是否相同
+func (program *Program) sameConfig(other *Program) bool {
+	a, b := *program, *other
+	a.ProcNum, b.ProcNum = 0, 0
+	a.processes, b.processes = nil, nil
+	a.active, b.active = false, false
+	a.stdoutLog, b.stdoutLog = nil, nil
+	a.stderrLog, b.stderrLog = nil, nil
+	a.sockets, b.sockets = nil, nil
+
+	if len(a.Environment) == 0 {
+		a.Environment = nil
+	}
+	if len(b.Environment) == 0 {
+		b.Environment = nil
+	}
+
+	return reflect.DeepEqual(a, b)
+}
+
+// release 关闭程序的日志文件和监听 socket，程序被移除、替换或者 Manager 停止时调用
+func (program *Program) release() {
+	program.closeLogs()
+	if program.sockets != nil {
+		program.sockets.close()
+	}
+}
+
 // Processes get all processes for the program
 func (program *Program) Processes() []*Process {
 	return program.processes
 }
+
+// splitCommand 将命令行拆分为可执行文件和参数，支持使用单引号和双引号包含空格的参数
+func splitCommand(command string) (string, []string, error) {
+	args := make([]string, 0)
+
+	var current strings.Builder
+	var quote rune
+	inArg, escaped := false, false
+	for _, c := range command {
+		switch {
+		case escaped:
+			current.WriteRune(c)
+			escaped = false
+		case c == '\\' && quote != '\'':
+			escaped, inArg = true, true
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			} else {
+				current.WriteRune(c)
+			}
+		case c == '\'' || c == '"':
+			quote, inArg = c, true
+		case c == ' ' || c == '\t' || c == '\n':
+			if inArg {
+				args = append(args, current.String())
+				current.Reset()
+				inArg = false
+			}
+		default:
+			current.WriteRune(c)
+			inArg = true
+		}
+	}
+
+	if quote != 0 {
+		return "", nil, fmt.Errorf("unterminated quote in command %q", command)
+	}
+
+	if inArg {
+		args = append(args, current.String())
+	}
+
+	if len(args) == 0 {
+		return "", nil, nil
+	}
+
+	return args[0], args[1:], nil
+}
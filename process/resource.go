@@ -0,0 +1,192 @@
+package process
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/mylxsw/asteria/log"
+)
+
+// DefaultSampleInterval 默认的资源使用情况采样间隔
+const DefaultSampleInterval = 5 * time.Second
+
+// ErrResourceUnsupported 当前平台不支持该资源限制或者资源采样
+var ErrResourceUnsupported = errors.New("not supported on this platform")
+
+// Resources 进程的资源限制配置，rlimit 和 cgroup 限制仅在 Linux 下有效，
+// 其中 rlimit 的软限制和硬限制设置为相同的值，0 表示不限制。
+// 配置了 rlimit 或者 cgroup 时，命令通过 /bin/sh 包装启动，在执行真正的命令之前完成资源限制的设置
+type Resources struct {
+	// MaxOpenFiles 最大打开文件数（RLIMIT_NOFILE）
+	MaxOpenFiles uint64 `json:"max_open_files,omitempty"`
+	// MaxProcesses 进程所属用户的最大进程数（RLIMIT_NPROC）
+	MaxProcesses uint64 `json:"max_processes,omitempty"`
+	// MaxAddressSpace 最大虚拟内存大小（RLIMIT_AS），单位为字节
+	MaxAddressSpace uint64 `json:"max_address_space,omitempty"`
+	// MaxCoreSize 最大 core 文件大小（RLIMIT_CORE），单位为字节
+	MaxCoreSize uint64 `json:"max_core_size,omitempty"`
+
+	// Cgroup cgroup v2 的父目录，相对路径基于 /sys/fs/cgroup，为空时不使用 cgroup，
+	// 每个进程会放置在该目录下名为 <program>-<index> 的子 cgroup 中，进程退出后删除
+	Cgroup string `json:"cgroup,omitempty"`
+	// MemoryMax cgroup 的内存上限（memory.max），单位为字节
+	MemoryMax int64 `json:"memory_max,omitempty"`
+	// CPUMax cgroup 可以使用的 CPU 核数（cpu.max），例如 0.5 表示半个核
+	CPUMax float64 `json:"cpu_max,omitempty"`
+
+	// MemoryThreshold 进程常驻内存（RSS）超过该值时重启进程（无论 RestartPolicy 如何配置），单位为字节，
+	// 只统计 Manager 直接启动的进程，不包含它创建的子进程，需要限制整个进程树时使用 MemoryMax
+	MemoryThreshold int64 `json:"memory_threshold,omitempty"`
+	// SampleInterval 资源使用情况的采样间隔，为 0 时使用 DefaultSampleInterval
+	SampleInterval time.Duration `json:"sample_interval,omitempty"`
+}
+
+// ResourceUsage 进程的资源使用情况，只统计 Manager 直接启动的进程，不包含它创建的子进程
+type ResourceUsage struct {
+	// CPUPercent 最近一个采样周期内的 CPU 使用率，100 表示占满一个核
+	CPUPercent float64 `json:"cpu_percent"`
+	// RSS 常驻内存大小，单位为字节
+	RSS int64 `json:"rss"`
+	// OpenFDs 打开的文件描述符数量
+	OpenFDs int `json:"open_fds"`
+}
+
+// processStats 从系统中读取的进程资源统计
+type processStats struct {
+	cpuTime time.Duration // 用户态和内核态 CPU 时间之和
+	rss     int64
+	fds     int
+}
+
+// validate 校验资源限制配置
+func (res *Resources) validate() error {
+	if res.MemoryMax < 0 {
+		return fmt.Errorf("memory_max must not be negative")
+	}
+	if res.CPUMax < 0 {
+		return fmt.Errorf("cpu_max must not be negative")
+	}
+	if res.MemoryThreshold < 0 {
+		return fmt.Errorf("memory_threshold must not be negative")
+	}
+	if res.SampleInterval < 0 {
+		return fmt.Errorf("sample_interval must not be negative")
+	}
+	if res.Cgroup == "" && (res.MemoryMax > 0 || res.CPUMax > 0) {
+		return fmt.Errorf("memory_max and cpu_max require cgroup")
+	}
+
+	return nil
+}
+
+// sampleInterval 资源使用情况的采样间隔
+func (res *Resources) sampleInterval() time.Duration {
+	if res == nil || res.SampleInterval <= 0 {
+		return DefaultSampleInterval
+	}
+
+	return res.SampleInterval
+}
+
+// limited 是否配置了 rlimit 或者 cgroup，需要在执行真正的命令之前设置
+func (res *Resources) limited() bool {
+	return res.MaxOpenFiles > 0 || res.MaxProcesses > 0 || res.MaxAddressSpace > 0 || res.MaxCoreSize > 0 || res.Cgroup != ""
+}
+
+// applyResources 为 gateResources 包装后启动的进程设置 rlimit 并放置到 cgroup 中，此时进程还没有执行真正的命令，
+// 返回进程退出后需要执行的清理函数
+func (process *Process) applyResources(pid int) func() {
+	res := process.getProgram().Resources
+	if res == nil {
+		return func() {}
+	}
+
+	if err := setRlimits(pid, res); err != nil {
+		log.Warningf("set rlimits for process %s failed: %s", process.GetName(), err)
+	}
+
+	if res.Cgroup == "" {
+		return func() {}
+	}
+
+	dir, err := joinCgroup(res, fmt.Sprintf("%s-%d", process.getProgram().Name, process.index), pid)
+	if err != nil {
+		log.Warningf("place process %s into cgroup failed: %s", process.GetName(), err)
+	}
+
+	return func() {
+		if dir == "" {
+			return
+		}
+
+		if err := removeCgroup(dir); err != nil {
+			log.Warningf("remove cgroup %s failed: %s", dir, err)
+		}
+	}
+}
+
+// watchResources 定期采样进程的资源使用情况，常驻内存超过阈值后结束进程，由 Manager 重新启动
+func (process *Process) watchResources(ctx context.Context, pid int) {
+	res := process.getProgram().Resources
+
+	last, err := readProcessStats(pid)
+	if err != nil {
+		if !errors.Is(err, ErrResourceUnsupported) {
+			log.Debugf("sample resource usage of process %s failed: %s", process.GetName(), err)
+		}
+		return
+	}
+	lastTime := time.Now()
+	process.setUsage(ResourceUsage{RSS: last.rss, OpenFDs: last.fds})
+
+	ticker := time.NewTicker(res.sampleInterval())
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		stats, err := readProcessStats(pid)
+		if err != nil || ctx.Err() != nil {
+			return
+		}
+
+		now := time.Now()
+		usage := ResourceUsage{RSS: stats.rss, OpenFDs: stats.fds}
+		if elapsed := now.Sub(lastTime); elapsed > 0 {
+			usage.CPUPercent = float64(stats.cpuTime-last.cpuTime) / float64(elapsed) * 100
+		}
+		last, lastTime = stats, now
+		process.setUsage(usage)
+
+		if res != nil && res.MemoryThreshold > 0 && stats.rss >= res.MemoryThreshold {
+			process.lock.Lock()
+			stopRequested := process.stopRequested
+			if !stopRequested {
+				process.forceRestart = true
+			}
+			exited := process.exited
+			process.lock.Unlock()
+
+			if !stopRequested {
+				log.Errorf("process %s uses %d bytes memory, exceeds threshold %d, restarting", process.GetName(), stats.rss, res.MemoryThreshold)
+				process.terminate(pid, exited, DefaultStopTimeout)
+			}
+
+			return
+		}
+	}
+}
+
+// setUsage 更新进程的资源使用情况
+func (process *Process) setUsage(usage ResourceUsage) {
+	process.lock.Lock()
+	defer process.lock.Unlock()
+
+	process.usage = usage
+}
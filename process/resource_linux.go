@@ -0,0 +1,178 @@
+//go:build linux
+// +build linux
+
+package process
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+const (
+	// clockTicks /proc/<pid>/stat 中 CPU 时间的单位（USER_HZ），Linux 下固定为 100
+	clockTicks = 100
+	// cgroupRoot cgroup v2 的挂载点
+	cgroupRoot = "/sys/fs/cgroup"
+	// cpuPeriod 写入 cpu.max 时使用的周期，单位为微秒
+	cpuPeriod = 100000
+
+	rlimitCore   = 4
+	rlimitNproc  = 6
+	rlimitNofile = 7
+	rlimitAS     = 9
+)
+
+// setRlimits 为已经启动的进程设置 rlimit
+func setRlimits(pid int, res *Resources) error {
+	limits := []struct {
+		name     string
+		resource int
+		value    uint64
+	}{
+		{"max_open_files", rlimitNofile, res.MaxOpenFiles},
+		{"max_processes", rlimitNproc, res.MaxProcesses},
+		{"max_address_space", rlimitAS, res.MaxAddressSpace},
+		{"max_core_size", rlimitCore, res.MaxCoreSize},
+	}
+
+	for _, limit := range limits {
+		if limit.value == 0 {
+			continue
+		}
+
+		if err := prlimit(pid, limit.resource, limit.value); err != nil {
+			return fmt.Errorf("%s: %w", limit.name, err)
+		}
+	}
+
+	return nil
+}
+
+// prlimit 设置指定进程的资源限制，软限制和硬限制相同
+func prlimit(pid int, resource int, value uint64) error {
+	limit := syscall.Rlimit{Cur: value, Max: value}
+	_, _, errno := syscall.RawSyscall6(syscall.SYS_PRLIMIT64, uintptr(pid), uintptr(resource), uintptr(unsafe.Pointer(&limit)), 0, 0, 0)
+	if errno != 0 {
+		return errno
+	}
+
+	return nil
+}
+
+// joinCgroup 创建进程的 cgroup，写入内存和 CPU 限制，然后将进程加入该 cgroup，返回 cgroup 目录
+func joinCgroup(res *Resources, name string, pid int) (string, error) {
+	parent := res.Cgroup
+	if !filepath.IsAbs(parent) {
+		parent = filepath.Join(cgroupRoot, parent)
+	}
+
+	if err := os.MkdirAll(parent, 0755); err != nil {
+		return "", err
+	}
+
+	// 父 cgroup 需要开启 memory 和 cpu 控制器，子 cgroup 才能设置对应的限制，已经开启或者没有权限时忽略错误
+	_ = ioutil.WriteFile(filepath.Join(parent, "cgroup.subtree_control"), []byte("+memory +cpu"), 0644)
+
+	dir := filepath.Join(parent, name)
+	if err := os.Mkdir(dir, 0755); err != nil && !os.IsExist(err) {
+		return "", err
+	}
+
+	if res.MemoryMax > 0 {
+		if err := writeCgroupFile(dir, "memory.max", strconv.FormatInt(res.MemoryMax, 10)); err != nil {
+			return dir, err
+		}
+	}
+
+	if res.CPUMax > 0 {
+		quota := int64(res.CPUMax * cpuPeriod)
+		if err := writeCgroupFile(dir, "cpu.max", fmt.Sprintf("%d %d", quota, cpuPeriod)); err != nil {
+			return dir, err
+		}
+	}
+
+	return dir, writeCgroupFile(dir, "cgroup.procs", strconv.Itoa(pid))
+}
+
+// writeCgroupFile 写入 cgroup 控制文件
+func writeCgroupFile(dir string, file string, value string) error {
+	return ioutil.WriteFile(filepath.Join(dir, file), []byte(value), 0644)
+}
+
+// removeCgroup 删除进程的 cgroup，进程刚退出时 cgroup 可能还没有被内核释放，这里进行短暂的重试
+func removeCgroup(dir string) error {
+	var err error
+	for i := 0; i < 10; i++ {
+		if err = os.Remove(dir); err == nil || os.IsNotExist(err) {
+			return nil
+		}
+
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	return err
+}
+
+// readProcessStats 从 /proc 中读取进程的 CPU 时间、常驻内存以及打开的文件描述符数量
+func readProcessStats(pid int) (processStats, error) {
+	var stats processStats
+	procDir := filepath.Join("/proc", strconv.Itoa(pid))
+
+	stat, err := ioutil.ReadFile(filepath.Join(procDir, "stat"))
+	if err != nil {
+		return stats, err
+	}
+
+	// 第二个字段为进程名称，可能包含空格和括号，从最后一个右括号之后开始解析
+	pos := bytes.LastIndexByte(stat, ')')
+	if pos < 0 {
+		return stats, fmt.Errorf("invalid stat for process %d", pid)
+	}
+
+	// 右括号之后的第一个字段为第 3 个字段（state），utime 和 stime 分别为第 14、15 个字段
+	fields := strings.Fields(string(stat[pos+1:]))
+	if len(fields) < 13 {
+		return stats, fmt.Errorf("invalid stat for process %d", pid)
+	}
+
+	utime, err := strconv.ParseUint(fields[11], 10, 64)
+	if err != nil {
+		return stats, err
+	}
+	stime, err := strconv.ParseUint(fields[12], 10, 64)
+	if err != nil {
+		return stats, err
+	}
+	stats.cpuTime = time.Duration(utime+stime) * time.Second / clockTicks
+
+	statm, err := ioutil.ReadFile(filepath.Join(procDir, "statm"))
+	if err != nil {
+		return stats, err
+	}
+
+	statmFields := strings.Fields(string(statm))
+	if len(statmFields) < 2 {
+		return stats, fmt.Errorf("invalid statm for process %d", pid)
+	}
+
+	pages, err := strconv.ParseInt(statmFields[1], 10, 64)
+	if err != nil {
+		return stats, err
+	}
+	stats.rss = pages * int64(os.Getpagesize())
+
+	// 没有权限读取其它用户进程的文件描述符时，忽略该指标
+	if fds, err := ioutil.ReadDir(filepath.Join(procDir, "fd")); err == nil {
+		stats.fds = len(fds)
+	}
+
+	return stats, nil
+}
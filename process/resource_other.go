@@ -0,0 +1,28 @@
+//go:build !linux
+// +build !linux
+
+package process
+
+// setRlimits 非 Linux 平台不支持为其它进程设置 rlimit
+func setRlimits(pid int, res *Resources) error {
+	if res.MaxOpenFiles > 0 || res.MaxProcesses > 0 || res.MaxAddressSpace > 0 || res.MaxCoreSize > 0 {
+		return ErrResourceUnsupported
+	}
+
+	return nil
+}
+
+// joinCgroup 非 Linux 平台不支持 cgroup
+func joinCgroup(res *Resources, name string, pid int) (string, error) {
+	return "", ErrResourceUnsupported
+}
+
+// removeCgroup 非 Linux 平台不支持 cgroup
+func removeCgroup(dir string) error {
+	return nil
+}
+
+// readProcessStats 非 Linux 平台不支持资源采样
+func readProcessStats(pid int) (processStats, error) {
+	return processStats{}, ErrResourceUnsupported
+}
@@ -0,0 +1,200 @@
+package process
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestResourceLimitsAndUsage(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("resource limits are only supported on linux")
+	}
+
+	dir, err := ioutil.TempDir("", "resources")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// 资源限制在执行真正的命令之前设置，包装命令使用的管道不会传递给程序，继承的 socket 不受影响
+	program := NewProgram("limited", `/bin/sh -c 'ulimit -n; [ -S /dev/fd/3 ] && echo socket; [ -e /dev/fd/4 ] || echo closed; exec sleep 10'`, "", 1)
+	program.Resources = &Resources{MaxOpenFiles: 256, SampleInterval: 20 * time.Millisecond}
+	program.Sockets = []*Socket{{Network: "unix", Address: filepath.Join(dir, "limited.sock")}}
+
+	manager := NewManager(time.Second, nil)
+	if err := manager.AddPrograms(program); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		manager.Watch(ctx)
+		close(done)
+	}()
+
+	proc := program.Processes()[0]
+	waitFor(t, "resource usage sampled", func() bool {
+		status := proc.Status()
+		return status.PID > 0 && status.RSS > 0 && status.OpenFDs > 0
+	})
+
+	waitFor(t, "process output", func() bool { return len(proc.Tail(0)) >= 3 })
+	output := proc.Tail(0)
+	if output[0].Line != "256" || output[1].Line != "socket" || output[2].Line != "closed" {
+		t.Errorf("expect limits applied before command executed, got %+v", output)
+	}
+
+	limits, err := ioutil.ReadFile(filepath.Join("/proc", strconv.Itoa(proc.GetPID()), "limits"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !regexp.MustCompile(`Max open files\s+256\s+256`).Match(limits) {
+		t.Errorf("expect max open files limited to 256, got\n%s", limits)
+	}
+
+	cancel()
+	<-done
+
+	if status := proc.Status(); status.RSS != 0 || status.OpenFDs != 0 {
+		t.Errorf("expect resource usage cleared after process stopped, got %+v", status)
+	}
+}
+
+func TestResourceGate(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("resource limits are only supported on linux")
+	}
+
+	// 放行之前子进程不会执行真正的命令，延迟设置的 rlimit 同样在命令执行之前生效
+	cmd := exec.Command("/bin/sh", "-c", "ulimit -n")
+	openGate, err := gateResources(cmd, &Resources{MaxOpenFiles: 128})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var output bytes.Buffer
+	cmd.Stdout = &output
+	if err := cmd.Start(); err != nil {
+		openGate()
+		t.Fatal(err)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	if err := setRlimits(cmd.Process.Pid, &Resources{MaxOpenFiles: 128}); err != nil {
+		t.Error(err)
+	}
+	openGate()
+
+	if err := cmd.Wait(); err != nil || strings.TrimSpace(output.String()) != "128" {
+		t.Errorf("expect open files limited to 128 before command executed, got %q, %v", output.String(), err)
+	}
+}
+
+func TestMemoryThresholdRestart(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("resource sampling is only supported on linux")
+	}
+
+	program := NewProgram("greedy", "/bin/sleep 10", "", 1)
+	program.RestartPolicy = RestartNever
+	program.BackoffInitial, program.BackoffMax = 10*time.Millisecond, 10*time.Millisecond
+	program.Resources = &Resources{MemoryThreshold: 1, SampleInterval: 20 * time.Millisecond}
+
+	manager := NewManager(time.Second, nil)
+	if err := manager.AddPrograms(program); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		manager.Watch(ctx)
+		close(done)
+	}()
+
+	proc := program.Processes()[0]
+	waitFor(t, "process restarted after exceeding memory threshold", func() bool {
+		status := proc.Status()
+		return status.PID > 0 && status.Restarts >= 1
+	})
+
+	cancel()
+	<-done
+}
+
+func TestJoinCgroup(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("cgroup is only supported on linux")
+	}
+
+	// 使用临时目录模拟 cgroup 文件系统，校验写入的控制文件内容
+	parent, err := ioutil.TempDir("", "cgroup")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(parent)
+
+	res := &Resources{Cgroup: parent, MemoryMax: 64 << 20, CPUMax: 0.5}
+	dir, err := joinCgroup(res, "web-0", 1234)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if dir != filepath.Join(parent, "web-0") {
+		t.Errorf("unexpected cgroup dir %s", dir)
+	}
+
+	for file, expect := range map[string]string{"memory.max": "67108864", "cpu.max": "50000 100000", "cgroup.procs": "1234"} {
+		data, err := ioutil.ReadFile(filepath.Join(dir, file))
+		if err != nil || string(data) != expect {
+			t.Errorf("expect %s to be %q, got %q (%v)", file, expect, data, err)
+		}
+	}
+}
+
+func TestParseResourcesConfig(t *testing.T) {
+	config, err := ParseConfig([]byte(`
+programs:
+  - name: worker
+    command: /bin/sleep 10
+    resources:
+      max_open_files: 1024
+      cgroup: workers
+      memory_max: 512MB
+      cpu_max: 1.5
+      memory_threshold: 400MB
+      sample_interval: 2
+`), FormatYAML)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res := config.Programs[0].Resources
+	if res == nil || res.MaxOpenFiles != 1024 || res.Cgroup != "workers" || res.MemoryMax != 512<<20 ||
+		res.CPUMax != 1.5 || res.MemoryThreshold != 400<<20 || res.SampleInterval != 2*time.Second {
+		t.Errorf("unexpected resources %+v", res)
+	}
+
+	config, err = ParseConfig([]byte("[program:worker]\ncommand=/bin/sleep 10\nmax_open_files=64\nmemory_threshold=1GB\n"), FormatINI)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res := config.Programs[0].Resources; res == nil || res.MaxOpenFiles != 64 || res.MemoryThreshold != 1<<30 {
+		t.Errorf("unexpected resources %+v", res)
+	}
+
+	if _, err := ParseConfig([]byte("[program:worker]\ncommand=/bin/sleep 10\nmemory_max=1GB\n"), FormatINI); err == nil {
+		t.Errorf("expect error for memory_max without cgroup")
+	}
+}
@@ -0,0 +1,157 @@
+package process
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// RestartPolicy 进程退出后的重启策略
+type RestartPolicy string
+
+const (
+	// RestartAlways 进程退出后总是重启
+	RestartAlways RestartPolicy = "always"
+	// RestartOnFailure 只有进程的退出码不在 Program.ExitCodes 中时才重启
+	RestartOnFailure RestartPolicy = "on-failure"
+	// RestartNever 进程退出后不再重启
+	RestartNever RestartPolicy = "never"
+)
+
+// ParseRestartPolicy 解析重启策略，兼容 supervisord 的 autorestart 配置：true、false、unexpected
+func ParseRestartPolicy(policy string) (RestartPolicy, error) {
+	switch strings.ToLower(strings.TrimSpace(policy)) {
+	case "always", "true":
+		return RestartAlways, nil
+	case "on-failure", "unexpected":
+		return RestartOnFailure, nil
+	case "never", "false":
+		return RestartNever, nil
+	}
+
+	return "", fmt.Errorf("invalid restart policy %q", policy)
+}
+
+// defaultProgram 没有关联 Program 的进程使用的默认配置
+var defaultProgram = NewProgram("", "", "", 1)
+
+// getProgram 获取进程所属的程序配置
+func (process *Process) getProgram() *Program {
+	if process.program != nil {
+		return process.program
+	}
+
+	return defaultProgram
+}
+
+// resetWindow 进程持续运行超过该时间后，重启计数清零
+func (program *Program) resetWindow() time.Duration {
+	if program.ResetWindow > 0 {
+		return program.ResetWindow
+	}
+
+	return program.StartSecs
+}
+
+// isExpectedExit 判断退出码是否为预期的退出码
+func (program *Program) isExpectedExit(exitCode int) bool {
+	for _, code := range program.ExitCodes {
+		if code == exitCode {
+			return true
+		}
+	}
+
+	return false
+}
+
+// backoff 计算第 n 次连续重启前的等待时间，每次翻倍，不超过 BackoffMax
+func (program *Program) backoff(n int) time.Duration {
+	delay := program.BackoffInitial
+	for i := 1; i < n && delay < program.BackoffMax; i++ {
+		delay *= 2
+	}
+
+	if delay > program.BackoffMax {
+		delay = program.BackoffMax
+	}
+
+	return delay
+}
+
+// nextRestart 进程退出后，根据重启策略判断是否需要重启以及重启前的等待时间，
+// 不需要重启时，reason 为不重启的原因
+func (process *Process) nextRestart() (delay time.Duration, restart bool, reason string) {
+	program := process.getProgram()
+
+	process.lock.Lock()
+	defer process.lock.Unlock()
+
+	alive, exitCode := process.lastAliveTime, process.exitCode
+
+	if alive >= program.StartSecs {
+		process.startFailures = 0
+	} else {
+		process.startFailures++
+	}
+
+	stable := alive >= program.resetWindow()
+	if stable {
+		process.restarts = 0
+	}
+
+	// 因为健康检查失败或者内存超限而被结束的进程，无论重启策略如何都需要重启
+	forceRestart := process.forceRestart
+	process.forceRestart = false
+
+	if !forceRestart {
+		switch program.RestartPolicy {
+		case RestartNever:
+			return 0, false, fmt.Sprintf("exited with code %d, restart policy is %s", exitCode, program.RestartPolicy)
+		case RestartOnFailure:
+			if program.isExpectedExit(exitCode) {
+				return 0, false, fmt.Sprintf("exited with expected code %d", exitCode)
+			}
+		}
+	}
+
+	if program.StartRetries != RetryForever && process.startFailures > program.StartRetries {
+		process.setStateLocked(StateFatal)
+		return 0, false, fmt.Sprintf("exited too quickly, gave up after %d retries", program.StartRetries)
+	}
+
+	if stable {
+		return 0, true, ""
+	}
+
+	if program.MaxRestarts != RetryForever && process.restarts >= program.MaxRestarts {
+		process.setStateLocked(StateFatal)
+		return 0, false, fmt.Sprintf("reached max restarts %d", program.MaxRestarts)
+	}
+
+	process.restarts++
+	return program.backoff(process.restarts), true, ""
+}
+
+// IsFatal 进程是否因为连续启动失败或者重启次数达到上限而进入 FATAL 状态
+func (process *Process) IsFatal() bool {
+	process.lock.Lock()
+	defer process.lock.Unlock()
+
+	return process.state == StateFatal
+}
+
+// GetRestarts 获取进程自上次稳定运行以来的连续重启次数
+func (process *Process) GetRestarts() int {
+	process.lock.Lock()
+	defer process.lock.Unlock()
+
+	return process.restarts
+}
+
+// GetExitCode 获取进程最后一次退出时的退出码，进程被信号终止或者启动失败时为 -1
+func (process *Process) GetExitCode() int {
+	process.lock.Lock()
+	defer process.lock.Unlock()
+
+	return process.exitCode
+}
@@ -0,0 +1,158 @@
+package process
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestBackoff(t *testing.T) {
+	program := NewProgram("test", "/bin/false", "", 1)
+	program.BackoffInitial = time.Second
+	program.BackoffMax = 10 * time.Second
+
+	for n, expect := range map[int]time.Duration{1: time.Second, 2: 2 * time.Second, 4: 8 * time.Second, 5: 10 * time.Second, 100: 10 * time.Second} {
+		if delay := program.backoff(n); delay != expect {
+			t.Errorf("backoff(%d): expect %s, got %s", n, expect, delay)
+		}
+	}
+}
+
+func TestNextRestart(t *testing.T) {
+	program := NewProgram("test", "/bin/false", "", 1)
+	program.StartSecs = time.Second
+	program.BackoffInitial = time.Second
+	program.BackoffMax = 3 * time.Second
+	program.MaxRestarts = 3
+	program.ResetWindow = time.Minute
+
+	process := NewProcess("test/0", "/bin/false", nil, "")
+	process.program = program
+
+	exit := func(alive time.Duration, code int) (time.Duration, bool) {
+		process.lastAliveTime, process.exitCode = alive, code
+		delay, restart, _ := process.nextRestart()
+		return delay, restart
+	}
+
+	// 未能稳定运行，等待时间指数增长
+	for i, expect := range []time.Duration{time.Second, 2 * time.Second, 3 * time.Second} {
+		if delay, restart := exit(10*time.Second, 1); !restart || delay != expect {
+			t.Errorf("restart %d: expect delay %s, got %s (restart=%v)", i+1, expect, delay, restart)
+		}
+	}
+
+	// 稳定运行后重启计数清零，立即重启
+	if delay, restart := exit(2*time.Minute, 1); !restart || delay != 0 || process.GetRestarts() != 0 {
+		t.Errorf("expect immediate restart after stable running, got delay=%s, restart=%v, restarts=%d", delay, restart, process.GetRestarts())
+	}
+
+	// 达到最大重启次数后进入 FATAL 状态
+	for i := 0; i < 3; i++ {
+		exit(10*time.Second, 1)
+	}
+	if _, restart := exit(10*time.Second, 1); restart || !process.IsFatal() {
+		t.Errorf("expect fatal after max restarts, got restart=%v, fatal=%v", restart, process.IsFatal())
+	}
+
+	// on-failure 策略下，预期的退出码不重启
+	process = NewProcess("test/1", "/bin/false", nil, "")
+	process.program = program
+	program.RestartPolicy = RestartOnFailure
+	program.ExitCodes = []int{0, 2}
+	if _, restart := exit(2*time.Minute, 2); restart || process.IsFatal() {
+		t.Errorf("expect no restart for expected exit code")
+	}
+	if _, restart := exit(2*time.Minute, 1); !restart {
+		t.Errorf("expect restart for unexpected exit code")
+	}
+
+	program.RestartPolicy = RestartNever
+	if _, restart := exit(2*time.Minute, 1); restart {
+		t.Errorf("expect no restart with never policy")
+	}
+}
+
+func TestManagerMaxRestarts(t *testing.T) {
+	program := NewProgram("false", "/bin/sh -c 'exit 3'", "", 1)
+	program.BackoffInitial = 10 * time.Millisecond
+	program.BackoffMax = 10 * time.Millisecond
+	program.MaxRestarts = 2
+
+	manager := NewManager(time.Second, nil)
+	if err := manager.AddPrograms(program); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+
+	manager.Watch(ctx)
+
+	proc := program.Processes()[0]
+	if !proc.IsFatal() || proc.GetRestarts() != 2 || proc.GetExitCode() != 3 {
+		t.Errorf("expect fatal after 2 restarts with exit code 3, got fatal=%v, restarts=%d, exit code=%d", proc.IsFatal(), proc.GetRestarts(), proc.GetExitCode())
+	}
+}
+
+func TestRestartStoppedProgramKeepsPolicy(t *testing.T) {
+	program := NewProgram("false", "/bin/sh -c 'exit 1'", "", 1)
+	program.AutoStart = false
+	program.StartSecs = 0
+	program.RestartPolicy = RestartNever
+
+	manager := NewManager(time.Second, nil)
+	if err := manager.AddPrograms(program); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		manager.Watch(ctx)
+		close(done)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	// 重启没有在运行的程序，新的进程退出后仍然遵循重启策略
+	waitFor(t, "program restarted", func() bool { return manager.RestartProgram("false") == nil })
+
+	proc := program.Processes()[0]
+	waitFor(t, "process exited", func() bool {
+		state := proc.GetState()
+		return !proc.IsRunning() && (state == StateExited || state == StateFatal)
+	})
+
+	time.Sleep(100 * time.Millisecond)
+	if status := proc.Status(); status.State != StateExited && status.State != StateFatal || status.Restarts != 0 {
+		t.Errorf("expect process exited without restart, got %+v", status)
+	}
+}
+
+func TestParseRestartConfig(t *testing.T) {
+	config, err := ParseConfig([]byte("[program:a]\ncommand=/bin/true\nautorestart=false\n\n[program:b]\ncommand=/bin/true\nexitcodes=0,2\nbackoffsecs=2\nmaxbackoffsecs=30\nmaxrestarts=5\n"), FormatINI)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a, b := config.Programs[0], config.Programs[1]
+	if a.RestartPolicy != RestartNever {
+		t.Errorf("expect never, got %s", a.RestartPolicy)
+	}
+
+	if b.RestartPolicy != RestartOnFailure || len(b.ExitCodes) != 2 || b.ExitCodes[1] != 2 || b.BackoffInitial != 2*time.Second || b.BackoffMax != 30*time.Second || b.MaxRestarts != 5 {
+		t.Errorf("unexpected program %+v", b)
+	}
+
+	config, err = ParseConfig([]byte("programs:\n  - name: a\n    command: /bin/true\n    autorestart: true\n"), FormatYAML)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if config.Programs[0].RestartPolicy != RestartAlways {
+		t.Errorf("expect always, got %s", config.Programs[0].RestartPolicy)
+	}
+}
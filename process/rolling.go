@@ -0,0 +1,143 @@
+package process
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/mylxsw/asteria/log"
+)
+
+// rollingCheckInterval 滚动重启时检查新进程是否就绪的间隔
+const rollingCheckInterval = 100 * time.Millisecond
+
+// RollingRestart 滚动重启程序的所有进程，每次重启 batchSize 个进程（小于 1 时为 1），
+// 等待这一批进程重新进入 RUNNING 状态（配置了健康检查时需要健康检查通过）后再重启下一批，
+// 保证重启过程中最多只有 batchSize 个进程不可用。
+// 某一批进程未能就绪（进入 FATAL、EXITED 状态，或者 ctx 超时、取消）时停止滚动重启并返回错误，
+// 还没有重启的进程保持原样运行
+func (manager *Manager) RollingRestart(ctx context.Context, name string, batchSize int) error {
+	if batchSize < 1 {
+		batchSize = 1
+	}
+
+	manager.lock.RLock()
+	program, err := manager.getProgram(name)
+	if err == nil && !manager.watching {
+		err = ErrNotWatching
+	}
+	var processes []*Process
+	if err == nil {
+		processes = append(processes, program.processes...)
+	}
+	watchDone := manager.watchDone
+	manager.lock.RUnlock()
+
+	if err != nil {
+		return err
+	}
+
+	for start := 0; start < len(processes); start += batchSize {
+		end := start + batchSize
+		if end > len(processes) {
+			end = len(processes)
+		}
+
+		begin := time.Now()
+		batch, err := manager.restartBatch(program, processes[start:end])
+		if err != nil {
+			return err
+		}
+
+		for _, process := range batch {
+			if err := waitReady(ctx, watchDone, process, begin); err != nil {
+				return fmt.Errorf("rolling restart of %s aborted: %w", name, err)
+			}
+		}
+
+		log.Debugf("rolling restart of %s: %d/%d processes restarted", name, end, len(processes))
+	}
+
+	return nil
+}
+
+// restartBatch 同时重启一批进程，等待旧进程全部退出后返回，已经被移除的进程会被忽略
+func (manager *Manager) restartBatch(program *Program, processes []*Process) ([]*Process, error) {
+	manager.lock.Lock()
+	if !manager.watching {
+		manager.lock.Unlock()
+		return nil, ErrNotWatching
+	}
+
+	if manager.programs[program.Name] != program {
+		manager.lock.Unlock()
+		return nil, fmt.Errorf("program %s changed during rolling restart", program.Name)
+	}
+
+	batch := make([]*Process, 0, len(processes))
+	for _, process := range processes {
+		if process.index < len(program.processes) && program.processes[process.index] == process {
+			batch = append(batch, process)
+		}
+	}
+
+	program.active = true
+	wait := manager.restartProcesses(batch)
+	manager.lock.Unlock()
+
+	wait()
+	return batch, nil
+}
+
+// restart 请求重启进程，正在运行的进程退出后立即重新启动，未运行的进程直接启动，
+// 返回旧的进程退出时关闭的 channel，调用前需要持有 manager.lock
+func (manager *Manager) restart(process *Process) chan struct{} {
+	process.lock.Lock()
+	process.restartRequested = true
+	process.lock.Unlock()
+
+	exited := process.requestStop()
+
+	// 进程没有在运行（或者只是在等待启动），不会收到退出通知，清除重启请求后直接启动，
+	// 否则新的进程退出时会忽略重启策略立即重启
+	process.lock.Lock()
+	running := process.running
+	if !running {
+		process.restartRequested = false
+	}
+	process.lock.Unlock()
+
+	if !running {
+		manager.launch(process)
+	}
+
+	return exited
+}
+
+// waitReady 等待进程在 since 之后重新启动并就绪
+func waitReady(ctx context.Context, watchDone <-chan struct{}, process *Process, since time.Time) error {
+	ticker := time.NewTicker(rollingCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		status := process.Status()
+		if !status.StartedAt.Before(since) && process.ready() {
+			return nil
+		}
+
+		switch status.State {
+		case StateFatal, StateExited, StateStopped:
+			if !process.IsRunning() {
+				return fmt.Errorf("process %s is %s", status.Name, status.State)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("process %s is not ready: %w", status.Name, ctx.Err())
+		case <-watchDone:
+			return ErrNotWatching
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package process
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRollingRestart(t *testing.T) {
+	for _, batch := range []int{1, 2} {
+		program := NewProgram("worker", "/bin/sleep 10", "", 3)
+		program.StartSecs = 200 * time.Millisecond
+
+		manager := NewManager(time.Second, nil)
+		if err := manager.AddPrograms(program); err != nil {
+			t.Fatal(err)
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan struct{})
+		go func() {
+			manager.Watch(ctx)
+			close(done)
+		}()
+
+		waitFor(t, "all processes running", program.ready)
+
+		pids := make([]int, 0)
+		for _, proc := range program.Processes() {
+			pids = append(pids, proc.GetPID())
+		}
+
+		// 滚动重启过程中，不可用的进程数量不能超过 batch
+		var lock sync.Mutex
+		maxUnavailable := 0
+		stopSampling := make(chan struct{})
+		sampled := make(chan struct{})
+		go func() {
+			defer close(sampled)
+			for {
+				unavailable := 0
+				for _, proc := range program.Processes() {
+					if proc.GetState() != StateRunning {
+						unavailable++
+					}
+				}
+
+				lock.Lock()
+				if unavailable > maxUnavailable {
+					maxUnavailable = unavailable
+				}
+				lock.Unlock()
+
+				select {
+				case <-stopSampling:
+					return
+				case <-time.After(5 * time.Millisecond):
+				}
+			}
+		}()
+
+		if err := manager.RollingRestart(context.Background(), "worker", batch); err != nil {
+			t.Fatal(err)
+		}
+		close(stopSampling)
+		<-sampled
+
+		if maxUnavailable == 0 || maxUnavailable > batch {
+			t.Errorf("expect at most %d processes unavailable during rolling restart, got %d", batch, maxUnavailable)
+		}
+
+		for i, proc := range program.Processes() {
+			status := proc.Status()
+			if status.State != StateRunning || status.PID == pids[i] || status.Restarts != 1 {
+				t.Errorf("expect process %s restarted once, got %+v (old pid %d)", status.Name, status, pids[i])
+			}
+		}
+
+		cancel()
+		<-done
+	}
+}
+
+func TestRollingRestartAbort(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rolling")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// 标记文件存在后，新启动的进程会立即退出
+	marker := filepath.Join(dir, "broken")
+	program := NewProgram("worker", "/bin/sh -c 'test -f "+marker+" || exec sleep 10'", "", 3)
+	program.StartSecs = 100 * time.Millisecond
+	program.RestartPolicy = RestartNever
+
+	manager := NewManager(time.Second, nil)
+	if err := manager.AddPrograms(program); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := manager.RollingRestart(context.Background(), "worker", 1); err != ErrNotWatching {
+		t.Errorf("expect ErrNotWatching before watching, got %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		manager.Watch(ctx)
+		close(done)
+	}()
+
+	waitFor(t, "all processes running", program.ready)
+	pids := make([]int, 0)
+	for _, proc := range program.Processes() {
+		pids = append(pids, proc.GetPID())
+	}
+
+	if err := ioutil.WriteFile(marker, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := manager.RollingRestart(context.Background(), "worker", 1); err == nil {
+		t.Errorf("expect rolling restart aborted")
+	}
+
+	for i, proc := range program.Processes()[1:] {
+		if status := proc.Status(); status.State != StateRunning || status.PID != pids[i+1] {
+			t.Errorf("expect process %s untouched, got %+v", status.Name, status)
+		}
+	}
+
+	if err := os.Remove(marker); err != nil {
+		t.Fatal(err)
+	}
+
+	// 进程需要持续运行 StartSecs 后才进入 RUNNING 状态，超时时间小于 StartSecs 时滚动重启失败
+	timeout, cancelTimeout := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancelTimeout()
+	if err := manager.RollingRestart(timeout, "worker", 1); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expect rolling restart timeout, got %v", err)
+	}
+
+	cancel()
+	<-done
+}
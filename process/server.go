@@ -0,0 +1,235 @@
+package process
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/mylxsw/asteria/log"
+)
+
+// 控制服务支持的方法
+const (
+	MethodStatus         = "status"
+	MethodStart          = "start"
+	MethodStop           = "stop"
+	MethodRestart        = "restart"
+	MethodRollingRestart = "rolling-restart"
+	MethodTail           = "tail"
+)
+
+// rpcRequest 控制服务请求，每个请求为一行 JSON
+type rpcRequest struct {
+	ID     uint64    `json:"id"`
+	Method string    `json:"method"`
+	Params rpcParams `json:"params"`
+}
+
+// rpcParams 控制服务请求参数
+type rpcParams struct {
+	// Name 程序名称或者进程名称
+	Name string `json:"name,omitempty"`
+	// Lines tail 返回的行数
+	Lines int `json:"lines,omitempty"`
+	// Batch rolling-restart 每批重启的进程数量
+	Batch int `json:"batch,omitempty"`
+	// Timeout rolling-restart 的超时时间，为 0 时不限制
+	Timeout time.Duration `json:"timeout,omitempty"`
+}
+
+// rpcResponse 控制服务响应，每个响应为一行 JSON
+type rpcResponse struct {
+	ID     uint64          `json:"id"`
+	Result json.RawMessage `json:"result,omitempty"`
+	Error  string          `json:"error,omitempty"`
+}
+
+// ControlServer 进程管理器的控制服务，通过 Unix Socket 提供 JSON-RPC 风格的接口，
+// 支持 status、start、stop、restart、rolling-restart、tail 方法
+type ControlServer struct {
+	manager *Manager
+	path    string
+
+	lock  sync.Mutex
+	conns map[net.Conn]struct{}
+}
+
+// NewControlServer 创建一个控制服务，path 为 Unix Socket 文件路径
+func NewControlServer(manager *Manager, path string) *ControlServer {
+	return &ControlServer{
+		manager: manager,
+		path:    path,
+		conns:   make(map[net.Conn]struct{}),
+	}
+}
+
+// Serve 监听 Unix Socket 并处理请求，直到 ctx 结束，结束时删除 Socket 文件
+func (server *ControlServer) Serve(ctx context.Context) error {
+	if err := removeStaleSocket(server.path); err != nil {
+		return err
+	}
+
+	// 只允许当前用户访问控制服务
+	listener, err := listenPrivate(server.path)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(server.path)
+
+	done := make(chan struct{})
+	defer close(done)
+
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-done:
+		}
+
+		listener.Close()
+		server.closeConns()
+	}()
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+
+			return err
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			server.handle(conn)
+		}()
+	}
+}
+
+// handle 处理单个连接上的所有请求
+func (server *ControlServer) handle(conn net.Conn) {
+	server.lock.Lock()
+	server.conns[conn] = struct{}{}
+	server.lock.Unlock()
+
+	defer func() {
+		server.lock.Lock()
+		delete(server.conns, conn)
+		server.lock.Unlock()
+
+		conn.Close()
+	}()
+
+	decoder := json.NewDecoder(conn)
+	encoder := json.NewEncoder(conn)
+	for {
+		var req rpcRequest
+		if err := decoder.Decode(&req); err != nil {
+			return
+		}
+
+		resp := rpcResponse{ID: req.ID}
+		result, err := server.call(req)
+		if err == nil {
+			resp.Result, err = json.Marshal(result)
+		}
+
+		if err != nil {
+			resp.Error = err.Error()
+		}
+
+		if err := encoder.Encode(resp); err != nil {
+			log.Warningf("control server write response failed: %s", err)
+			return
+		}
+	}
+}
+
+// call 执行请求的方法
+func (server *ControlServer) call(req rpcRequest) (interface{}, error) {
+	manager, name := server.manager, req.Params.Name
+
+	switch req.Method {
+	case MethodStatus:
+		return filterStatus(manager.Status(), name)
+	case MethodStart:
+		return nil, manager.StartProgram(name)
+	case MethodStop:
+		return nil, manager.StopProgram(name)
+	case MethodRestart:
+		return nil, manager.RestartProgram(name)
+	case MethodRollingRestart:
+		ctx := context.Background()
+		if req.Params.Timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, req.Params.Timeout)
+			defer cancel()
+		}
+
+		return nil, manager.RollingRestart(ctx, name, req.Params.Batch)
+	case MethodTail:
+		return manager.Tail(name, req.Params.Lines)
+	}
+
+	return nil, fmt.Errorf("unknown method %q", req.Method)
+}
+
+// closeConns 关闭所有连接
+func (server *ControlServer) closeConns() {
+	server.lock.Lock()
+	defer server.lock.Unlock()
+
+	for conn := range server.conns {
+		conn.Close()
+	}
+}
+
+// filterStatus 只保留指定程序或者进程的状态，name 为空时返回全部
+func filterStatus(status []ProcessStatus, name string) ([]ProcessStatus, error) {
+	if name == "" {
+		return status, nil
+	}
+
+	result := make([]ProcessStatus, 0)
+	for _, st := range status {
+		if st.Program == name || st.Name == name {
+			result = append(result, st)
+		}
+	}
+
+	if len(result) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrProgramNotFound, name)
+	}
+
+	return result, nil
+}
+
+// removeStaleSocket 删除上次运行遗留的 Socket 文件，如果 Socket 仍然有服务在监听，或者 path 不是 Socket 文件，返回错误
+func removeStaleSocket(path string) error {
+	info, err := os.Lstat(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if info.Mode()&os.ModeSocket == 0 {
+		return fmt.Errorf("%s already exists and is not a socket", path)
+	}
+
+	if conn, err := net.Dial("unix", path); err == nil {
+		conn.Close()
+		return fmt.Errorf("socket %s is in use", path)
+	}
+
+	return os.Remove(path)
+}
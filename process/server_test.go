@@ -0,0 +1,158 @@
+package process
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestControlServer(t *testing.T) {
+	dir, err := ioutil.TempDir("", "process_control")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	manager := NewManager(time.Second, nil)
+	if err := manager.AddPrograms(
+		NewProgram("sleep", "/bin/sleep 10", "", 2),
+		NewProgram("echo", "/bin/sh -c 'echo hello; echo world >&2; sleep 10'", "", 1),
+	); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	watchDone := make(chan struct{})
+	go func() {
+		manager.Watch(ctx)
+		close(watchDone)
+	}()
+
+	socket := filepath.Join(dir, "process.sock")
+	serverDone := make(chan error)
+	go func() {
+		serverDone <- NewControlServer(manager, socket).Serve(ctx)
+	}()
+
+	var client *ControlClient
+	waitFor(t, "control server started", func() bool {
+		client, err = DialControl(socket)
+		return err == nil
+	})
+	defer client.Close()
+
+	if info, err := os.Stat(socket); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("expect socket only accessible by current user, got %v, %v", info.Mode(), err)
+	}
+
+	waitFor(t, "processes started", func() bool {
+		status, err := client.Status("")
+		if err != nil || len(status) != 3 {
+			return false
+		}
+
+		for _, st := range status {
+			if st.PID <= 0 {
+				return false
+			}
+		}
+		return true
+	})
+
+	if err := client.Stop("sleep"); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "program stopped", func() bool {
+		status, err := client.Status("sleep")
+		return err == nil && len(status) == 2 && status[0].State == StateStopped && status[1].State == StateStopped
+	})
+
+	if err := client.Start("sleep"); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Restart("sleep"); err != nil {
+		t.Fatal(err)
+	}
+
+	waitFor(t, "output captured", func() bool {
+		lines, err := client.Tail("echo/0", 10)
+		return err == nil && len(lines) == 2
+	})
+
+	lines, _ := client.Tail("echo", 1)
+	if len(lines) != 1 || lines[0].Process != "echo/0" || (lines[0].Line != "hello" && lines[0].Line != "world") {
+		t.Errorf("unexpected tail output %+v", lines)
+	}
+
+	if err := client.Start("not-exist"); err == nil || !strings.Contains(err.Error(), ErrProgramNotFound.Error()) {
+		t.Errorf("expect program not found error, got %v", err)
+	}
+
+	if err := client.call("unknown", rpcParams{}, nil); err == nil {
+		t.Errorf("expect error for unknown method")
+	}
+
+	cancel()
+	if err := <-serverDone; err != nil {
+		t.Errorf("expect server closed without error, got %s", err)
+	}
+	<-watchDone
+
+	if _, err := os.Stat(socket); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expect socket removed after server closed")
+	}
+}
+
+func TestRemoveStaleSocket(t *testing.T) {
+	dir, err := ioutil.TempDir("", "process_control")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// 不是 socket 的文件不能被删除
+	file := filepath.Join(dir, "config.yaml")
+	if err := ioutil.WriteFile(file, []byte("programs: []"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{file, dir} {
+		if err := removeStaleSocket(path); err == nil {
+			t.Errorf("expect error for non-socket %s", path)
+		}
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("expect %s untouched, got %s", path, err)
+		}
+	}
+
+	if err := NewControlServer(NewManager(time.Second, nil), file).Serve(context.Background()); err == nil {
+		t.Errorf("expect control server refuses to replace a regular file")
+	}
+
+	socket := filepath.Join(dir, "process.sock")
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := removeStaleSocket(socket); err == nil {
+		t.Errorf("expect error for socket in use")
+	}
+
+	// 监听者退出后遗留的 socket 文件被删除
+	listener.(*net.UnixListener).SetUnlinkOnClose(false)
+	listener.Close()
+	if err := removeStaleSocket(socket); err != nil {
+		t.Errorf("expect stale socket removed, got %s", err)
+	}
+	if _, err := os.Stat(socket); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expect stale socket removed")
+	}
+}
@@ -0,0 +1,238 @@
+package process
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// listenFDsStart 继承的文件描述符从 3 开始（0、1、2 为标准输入输出）
+const listenFDsStart = 3
+
+// Socket 由 Manager 创建并传递给子进程的监听 socket，与 systemd 的 socket activation 兼容：
+// 子进程从文件描述符 3 开始依次继承这些 socket，并通过环境变量 LISTEN_FDS、LISTEN_PID、LISTEN_FDNAMES
+// 获取 socket 的数量、接收者的 pid 以及名称。socket 在进程重启期间保持打开，新的连接会在内核中排队，
+// 等待重启后的进程处理，不会被拒绝
+type Socket struct {
+	// Name socket 名称，用于 LISTEN_FDNAMES，为空时使用 Address
+	Name string `json:"name,omitempty"`
+	// Network 网络类型，可以为 tcp、tcp4、tcp6 或者 unix
+	Network string `json:"network"`
+	// Address 监听地址，tcp 为 host:port，unix 为 socket 文件路径
+	Address string `json:"address"`
+}
+
+// String 返回 [name=]network://address 格式的 socket 描述
+func (socket *Socket) String() string {
+	spec := socket.Network + "://" + socket.Address
+	if socket.Name != "" {
+		spec = socket.Name + "=" + spec
+	}
+
+	return spec
+}
+
+// name socket 在 LISTEN_FDNAMES 中的名称
+func (socket *Socket) name() string {
+	if socket.Name != "" {
+		return socket.Name
+	}
+
+	return socket.Address
+}
+
+// validate 校验 socket 配置
+func (socket *Socket) validate() error {
+	switch socket.Network {
+	case "tcp", "tcp4", "tcp6", "unix":
+	default:
+		return fmt.Errorf("unsupported network %q", socket.Network)
+	}
+
+	if socket.Address == "" {
+		return fmt.Errorf("address is required")
+	}
+
+	if strings.ContainsAny(socket.Name, ":") {
+		return fmt.Errorf("name %q must not contain ':'", socket.Name)
+	}
+
+	return nil
+}
+
+// ParseSocket 解析 [name=]network://address 格式的 socket 描述，例如 http=tcp://:8080、unix:///var/run/app.sock
+func ParseSocket(spec string) (*Socket, error) {
+	spec = strings.TrimSpace(spec)
+
+	socket := &Socket{}
+	if pos := strings.Index(spec, "="); pos >= 0 && pos < strings.Index(spec, "://") {
+		socket.Name, spec = spec[:pos], spec[pos+1:]
+	}
+
+	parts := strings.SplitN(spec, "://", 2)
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("invalid socket %q, expect [name=]network://address", spec)
+	}
+
+	socket.Network, socket.Address = strings.ToLower(parts[0]), parts[1]
+	if err := socket.validate(); err != nil {
+		return nil, err
+	}
+
+	return socket, nil
+}
+
+// socketSet 程序的监听 socket，在第一个进程启动时创建，由程序的所有进程共享，程序被移除或者 Manager 停止时关闭
+type socketSet struct {
+	lock      sync.Mutex
+	listeners []net.Listener
+	files     []*os.File
+}
+
+// open 创建所有监听 socket，已经创建时直接返回，返回的文件用于传递给子进程
+func (set *socketSet) open(sockets []*Socket) ([]*os.File, error) {
+	set.lock.Lock()
+	defer set.lock.Unlock()
+
+	if set.files != nil || len(sockets) == 0 {
+		return set.files, nil
+	}
+
+	listeners := make([]net.Listener, 0, len(sockets))
+	files := make([]*os.File, 0, len(sockets))
+	closeAll := func() {
+		for _, listener := range listeners {
+			_ = listener.Close()
+		}
+		for _, file := range files {
+			_ = file.Close()
+		}
+	}
+
+	for _, socket := range sockets {
+		listener, file, err := listen(socket)
+		if err != nil {
+			closeAll()
+			return nil, fmt.Errorf("listen on %s failed: %w", socket, err)
+		}
+
+		listeners = append(listeners, listener)
+		files = append(files, file)
+	}
+
+	set.listeners, set.files = listeners, files
+	return files, nil
+}
+
+// close 关闭所有监听 socket
+func (set *socketSet) close() {
+	set.lock.Lock()
+	defer set.lock.Unlock()
+
+	for _, file := range set.files {
+		_ = file.Close()
+	}
+	for _, listener := range set.listeners {
+		_ = listener.Close()
+	}
+
+	set.listeners, set.files = nil, nil
+}
+
+// listen 创建监听 socket，并获取其文件描述符的副本
+func listen(socket *Socket) (net.Listener, *os.File, error) {
+	if socket.Network == "unix" {
+		if err := removeStaleSocket(socket.Address); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	listener, err := net.Listen(socket.Network, socket.Address)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	filer, ok := listener.(interface{ File() (*os.File, error) })
+	if !ok {
+		_ = listener.Close()
+		return nil, nil, fmt.Errorf("unsupported listener %T", listener)
+	}
+
+	file, err := filer.File()
+	if err != nil {
+		_ = listener.Close()
+		return nil, nil, err
+	}
+
+	return listener, file, nil
+}
+
+// listenEnv 生成传递给子进程的 LISTEN_FDS 和 LISTEN_FDNAMES 环境变量，
+// LISTEN_PID 需要等于子进程的 pid，由子进程启动时自行设置
+func listenEnv(sockets []*Socket) []string {
+	names := make([]string, 0, len(sockets))
+	for _, socket := range sockets {
+		names = append(names, socket.name())
+	}
+
+	return []string{
+		"LISTEN_FDS=" + strconv.Itoa(len(sockets)),
+		"LISTEN_FDNAMES=" + strings.Join(names, ":"),
+	}
+}
+
+// withoutListenEnv 移除环境变量中的 LISTEN_* 变量，避免将当前进程继承的 socket 信息传递给子进程
+func withoutListenEnv(env []string) []string {
+	result := make([]string, 0, len(env))
+	for _, item := range env {
+		if strings.HasPrefix(item, "LISTEN_PID=") || strings.HasPrefix(item, "LISTEN_FDS=") || strings.HasPrefix(item, "LISTEN_FDNAMES=") {
+			continue
+		}
+
+		result = append(result, item)
+	}
+
+	return result
+}
+
+// Listeners 获取由 Manager（或者 systemd）传递给当前进程的监听 socket，用于被管理的 Go 程序，
+// 返回的 map 的 key 为 socket 名称，没有继承 socket 时返回空的 map，调用后会清除 LISTEN_* 环境变量
+func Listeners() (map[string]net.Listener, error) {
+	listeners := make(map[string]net.Listener)
+
+	pid, err := strconv.Atoi(os.Getenv("LISTEN_PID"))
+	if err != nil || pid != os.Getpid() {
+		return listeners, nil
+	}
+
+	count, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if err != nil || count <= 0 {
+		return listeners, nil
+	}
+
+	names := strings.Split(os.Getenv("LISTEN_FDNAMES"), ":")
+	for _, key := range []string{"LISTEN_PID", "LISTEN_FDS", "LISTEN_FDNAMES"} {
+		_ = os.Unsetenv(key)
+	}
+
+	for i := 0; i < count; i++ {
+		name := strconv.Itoa(listenFDsStart + i)
+		if i < len(names) && names[i] != "" {
+			name = names[i]
+		}
+
+		file := os.NewFile(uintptr(listenFDsStart+i), name)
+		listener, err := net.FileListener(file)
+		_ = file.Close()
+		if err != nil {
+			return nil, fmt.Errorf("inherited socket %s: %w", name, err)
+		}
+
+		listeners[name] = listener
+	}
+
+	return listeners, nil
+}
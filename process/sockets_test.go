@@ -0,0 +1,182 @@
+package process
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseSocket(t *testing.T) {
+	socket, err := ParseSocket("http=tcp://127.0.0.1:8080")
+	if err != nil || socket.Name != "http" || socket.Network != "tcp" || socket.Address != "127.0.0.1:8080" {
+		t.Errorf("unexpected socket %+v, err %v", socket, err)
+	}
+
+	socket, err = ParseSocket("unix:///var/run/app.sock")
+	if err != nil || socket.Name != "" || socket.Network != "unix" || socket.Address != "/var/run/app.sock" || socket.name() != "/var/run/app.sock" {
+		t.Errorf("unexpected socket %+v, err %v", socket, err)
+	}
+
+	for _, spec := range []string{"127.0.0.1:8080", "udp://:53", "tcp://", "a:b=tcp://:80"} {
+		if _, err := ParseSocket(spec); err == nil {
+			t.Errorf("expect error for socket %q", spec)
+		}
+	}
+
+	config, err := ParseConfig([]byte("[program:web]\ncommand=/bin/sleep 10\nsockets=http=tcp://:8080, unix:///tmp/web.sock\n"), FormatINI)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sockets := config.Programs[0].Sockets; len(sockets) != 2 || sockets[0].String() != "http=tcp://:8080" || sockets[1].String() != "unix:///tmp/web.sock" {
+		t.Errorf("unexpected sockets %v", sockets)
+	}
+}
+
+func TestSocketActivation(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("socket activation is not supported on windows")
+	}
+
+	dir, err := ioutil.TempDir("", "sockets")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "web.sock")
+	program := NewProgram("web", `/bin/sh -c 'echo "$LISTEN_FDS $LISTEN_PID $$ $LISTEN_FDNAMES"; [ -S /dev/fd/3 ] && echo socket; exec sleep 10'`, "", 1)
+	program.StartSecs = 0
+	program.Sockets = []*Socket{{Name: "web", Network: "unix", Address: path}}
+
+	manager := NewManager(time.Second, nil)
+	if err := manager.AddPrograms(program); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		manager.Watch(ctx)
+		close(done)
+	}()
+
+	proc := program.Processes()[0]
+	waitFor(t, "process output", func() bool { return len(proc.Tail(0)) >= 2 })
+
+	output := proc.Tail(0)
+	fields := strings.Fields(output[0].Line)
+	if len(fields) != 4 || fields[0] != "1" || fields[1] != fields[2] || fields[1] != strconv.Itoa(proc.GetPID()) || fields[3] != "web" {
+		t.Errorf("unexpected listen env %q, pid %d", output[0].Line, proc.GetPID())
+	}
+	if output[1].Line != "socket" {
+		t.Errorf("expect fd 3 to be a socket, got %q", output[1].Line)
+	}
+
+	// 进程停止后 socket 保持打开，新的连接不会被拒绝
+	if err := manager.StopProgram("web"); err != nil {
+		t.Fatal(err)
+	}
+
+	conn, err := net.Dial("unix", path)
+	if err != nil {
+		t.Errorf("expect connection accepted while process stopped, got %s", err)
+	} else {
+		conn.Close()
+	}
+
+	cancel()
+	<-done
+
+	if _, err := net.Dial("unix", path); err == nil {
+		t.Errorf("expect socket closed after manager stopped")
+	}
+}
+
+func TestListenersInherited(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("socket activation is not supported on windows")
+	}
+
+	dir, err := ioutil.TempDir("", "sockets")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "echo.sock")
+	program := NewProgram("echo", os.Args[0]+" -test.run=TestListenersHelper", "", 1)
+	program.StartSecs = 0
+	program.AutoStart = false
+	program.Environment["PROCESS_LISTENERS_HELPER"] = "1"
+	program.Sockets = []*Socket{{Name: "echo", Network: "unix", Address: path}}
+
+	manager := NewManager(time.Second, nil)
+	if err := manager.AddPrograms(program); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		manager.Watch(ctx)
+		close(done)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	// 第一次启动时创建 socket，停止后 socket 保持打开，此时建立的连接由下一次启动的进程处理
+	waitFor(t, "program started", func() bool { return manager.StartProgram("echo") == nil })
+	waitFor(t, "process running", program.ready)
+	if err := manager.StopProgram("echo"); err != nil {
+		t.Fatal(err)
+	}
+
+	conn, err := net.Dial("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	if err := manager.StartProgram("echo"); err != nil {
+		t.Fatal(err)
+	}
+
+	_ = conn.SetReadDeadline(time.Now().Add(10 * time.Second))
+	data, err := ioutil.ReadAll(conn)
+	if err != nil || string(data) != "echo" {
+		t.Errorf("expect queued connection handled by restarted process, got %q, %v, status %+v", data, err, program.Processes()[0].Status())
+	}
+}
+
+// TestListenersHelper 作为 TestListenersInherited 的子进程运行，接受连接后返回 socket 名称
+func TestListenersHelper(t *testing.T) {
+	if os.Getenv("PROCESS_LISTENERS_HELPER") != "1" {
+		t.Skip("helper process")
+	}
+
+	listeners, err := Listeners()
+	if err != nil || len(listeners) != 1 {
+		t.Fatalf("unexpected listeners %v, err %v", listeners, err)
+	}
+
+	for name, listener := range listeners {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, _ = conn.Write([]byte(name))
+			conn.Close()
+		}
+	}
+}
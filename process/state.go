@@ -0,0 +1,225 @@
+package process
+
+import (
+	"sync"
+	"time"
+
+	"github.com/mylxsw/go-toolkit/events"
+)
+
+// State 进程状态，与 supervisord 的进程状态保持一致
+type State string
+
+const (
+	// StateStopped 进程未启动，或者已经被手动停止
+	StateStopped State = "STOPPED"
+	// StateStarting 进程已经启动，但是运行时间还没有达到 StartSecs
+	StateStarting State = "STARTING"
+	// StateRunning 进程正在运行
+	StateRunning State = "RUNNING"
+	// StateBackoff 进程退出后，正在等待重启
+	StateBackoff State = "BACKOFF"
+	// StateStopping 已经向进程发送停止信号，正在等待进程退出
+	StateStopping State = "STOPPING"
+	// StateExited 进程已经退出，并且根据重启策略不需要重启（或者即将立即重启）
+	StateExited State = "EXITED"
+	// StateFatal 进程连续启动失败或者重启次数达到上限，不再重启
+	StateFatal State = "FATAL"
+)
+
+// ProcessStateChangedEvent 进程状态变更事件
+type ProcessStateChangedEvent struct {
+	Process string
+	Program string
+	PID     int
+	From    State
+	To      State
+	Time    time.Time
+}
+
+// ProcessStatus 进程的运行状态
+type ProcessStatus struct {
+	Name    string `json:"name"`
+	Program string `json:"program"`
+	State   State  `json:"state"`
+	PID     int    `json:"pid"`
+	// StateChangedAt 最后一次状态变更的时间
+	StateChangedAt time.Time `json:"state_changed_at"`
+	// StartedAt 最后一次启动时间
+	StartedAt time.Time `json:"started_at,omitempty"`
+	// Uptime 进程本次运行的时间，进程没有运行时为 0
+	Uptime time.Duration `json:"uptime"`
+	// Restarts 进程被重启的总次数
+	Restarts  int    `json:"restarts"`
+	ExitCode  int    `json:"exit_code"`
+	LastError string `json:"last_error,omitempty"`
+	// Health 健康状态，程序没有配置健康检查时为空
+	Health         Health `json:"health,omitempty"`
+	HealthFailures int    `json:"health_failures,omitempty"`
+	HealthError    string `json:"health_error,omitempty"`
+	// CPUPercent 最近一个采样周期内的 CPU 使用率，100 表示占满一个核，进程没有运行时为 0
+	CPUPercent float64 `json:"cpu_percent"`
+	// RSS 常驻内存大小，单位为字节
+	RSS int64 `json:"rss"`
+	// OpenFDs 打开的文件描述符数量
+	OpenFDs int `json:"open_fds"`
+}
+
+// GetState 获取进程当前状态
+func (process *Process) GetState() State {
+	process.lock.Lock()
+	defer process.lock.Unlock()
+
+	return process.state
+}
+
+// Status 获取进程的运行状态
+func (process *Process) Status() ProcessStatus {
+	process.lock.Lock()
+	defer process.lock.Unlock()
+
+	status := ProcessStatus{
+		Name:           process.name,
+		State:          process.state,
+		PID:            process.pid,
+		StateChangedAt: process.stateChangedAt,
+		StartedAt:      process.startedAt,
+		Restarts:       process.totalRestarts,
+		ExitCode:       process.exitCode,
+		LastError:      process.lastErrorMessage,
+		Health:         process.health,
+		HealthFailures: process.healthFailures,
+		HealthError:    process.healthError,
+		CPUPercent:     process.usage.CPUPercent,
+		RSS:            process.usage.RSS,
+		OpenFDs:        process.usage.OpenFDs,
+	}
+
+	if process.program != nil {
+		status.Program = process.program.Name
+	}
+
+	if process.state == StateStarting || process.state == StateRunning || process.state == StateStopping {
+		status.Uptime = time.Since(process.startedAt)
+	}
+
+	return status
+}
+
+// setState 更新进程状态
+func (process *Process) setState(state State) {
+	process.lock.Lock()
+	defer process.lock.Unlock()
+
+	process.setStateLocked(state)
+}
+
+// setStateLocked 更新进程状态并发布状态变更事件，调用前需要持有 process.lock
+func (process *Process) setStateLocked(state State) {
+	from := process.state
+	if from == state {
+		return
+	}
+
+	process.state = state
+	process.stateChangedAt = time.Now()
+
+	if process.stateListener == nil {
+		return
+	}
+
+	evt := ProcessStateChangedEvent{
+		Process: process.name,
+		PID:     process.pid,
+		From:    from,
+		To:      state,
+		Time:    process.stateChangedAt,
+	}
+	if process.program != nil {
+		evt.Program = process.program.Name
+	}
+
+	process.stateListener(evt)
+}
+
+// Status 获取所有进程的运行状态，按照程序的启动优先级排序
+func (manager *Manager) Status() []ProcessStatus {
+	manager.lock.RLock()
+	defer manager.lock.RUnlock()
+
+	result := make([]ProcessStatus, 0)
+	for _, program := range manager.sortedPrograms() {
+		for _, process := range program.processes {
+			result = append(result, process.Status())
+		}
+	}
+
+	return result
+}
+
+// SetEventManager 设置事件管理器，进程状态变更时发布 ProcessStateChangedEvent 事件
+func (manager *Manager) SetEventManager(eventManager *events.EventManager) {
+	manager.notifier.lock.Lock()
+	defer manager.notifier.lock.Unlock()
+
+	manager.notifier.eventManager = eventManager
+}
+
+// SetStateChangeHandler 设置进程状态变更时执行的函数
+func (manager *Manager) SetStateChangeHandler(handler func(evt ProcessStateChangedEvent)) {
+	manager.notifier.lock.Lock()
+	defer manager.notifier.lock.Unlock()
+
+	manager.notifier.handler = handler
+}
+
+// stateNotifier 按照发生的顺序异步分发进程状态变更事件，
+// 避免事件处理函数中调用 Manager 的方法时发生死锁
+type stateNotifier struct {
+	lock         sync.Mutex
+	queue        []ProcessStateChangedEvent
+	dispatching  bool
+	handler      func(evt ProcessStateChangedEvent)
+	eventManager *events.EventManager
+}
+
+// push 将事件加入分发队列
+func (notifier *stateNotifier) push(evt ProcessStateChangedEvent) {
+	notifier.lock.Lock()
+	defer notifier.lock.Unlock()
+
+	if notifier.handler == nil && notifier.eventManager == nil {
+		return
+	}
+
+	notifier.queue = append(notifier.queue, evt)
+	if !notifier.dispatching {
+		notifier.dispatching = true
+		go notifier.dispatch()
+	}
+}
+
+// dispatch 依次分发队列中的事件，队列为空时退出
+func (notifier *stateNotifier) dispatch() {
+	for {
+		notifier.lock.Lock()
+		if len(notifier.queue) == 0 {
+			notifier.dispatching = false
+			notifier.lock.Unlock()
+			return
+		}
+
+		evt := notifier.queue[0]
+		notifier.queue = notifier.queue[1:]
+		handler, eventManager := notifier.handler, notifier.eventManager
+		notifier.lock.Unlock()
+
+		if handler != nil {
+			handler(evt)
+		}
+
+		if eventManager != nil {
+			eventManager.Publish(evt)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package process
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mylxsw/go-toolkit/events"
+)
+
+func TestProcessState(t *testing.T) {
+	sleep := NewProgram("sleep", "/bin/sleep 10", "", 1)
+	sleep.StartSecs = 100 * time.Millisecond
+
+	fail := NewProgram("fail", "/bin/sh -c 'exit 2'", "", 1)
+	fail.BackoffInitial = 10 * time.Millisecond
+	fail.BackoffMax = 10 * time.Millisecond
+	fail.MaxRestarts = 1
+
+	manager := NewManager(time.Second, nil)
+	if err := manager.AddPrograms(sleep, fail); err != nil {
+		t.Fatal(err)
+	}
+
+	var lock sync.Mutex
+	transitions := make(map[string][]State)
+	manager.SetStateChangeHandler(func(evt ProcessStateChangedEvent) {
+		lock.Lock()
+		defer lock.Unlock()
+
+		if len(transitions[evt.Program]) == 0 {
+			transitions[evt.Program] = append(transitions[evt.Program], evt.From)
+		}
+		transitions[evt.Program] = append(transitions[evt.Program], evt.To)
+	})
+
+	published := make(chan ProcessStateChangedEvent, 100)
+	eventManager := events.NewEventManager(events.NewMemoryEventStore(false))
+	eventManager.Listen(func(evt ProcessStateChangedEvent) {
+		published <- evt
+	})
+	manager.SetEventManager(eventManager)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		manager.Watch(ctx)
+		close(done)
+	}()
+
+	proc := sleep.Processes()[0]
+	waitFor(t, "process running", func() bool { return proc.GetState() == StateRunning })
+	waitFor(t, "process fatal", func() bool { return fail.Processes()[0].GetState() == StateFatal })
+
+	status := manager.Status()
+	if len(status) != 2 {
+		t.Fatalf("expect 2 process status, got %d", len(status))
+	}
+
+	for _, st := range status {
+		switch st.Program {
+		case "sleep":
+			if st.State != StateRunning || st.PID <= 0 || st.Uptime <= 0 || st.StartedAt.IsZero() {
+				t.Errorf("unexpected status %+v", st)
+			}
+		case "fail":
+			if st.State != StateFatal || st.PID != 0 || st.Restarts != 1 || st.ExitCode != 2 || st.Uptime != 0 {
+				t.Errorf("unexpected status %+v", st)
+			}
+		}
+	}
+
+	if err := manager.StopProgram("sleep"); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "process stopped", func() bool { return proc.GetState() == StateStopped })
+
+	cancel()
+	<-done
+
+	expects := map[string][]State{
+		"sleep": {StateStopped, StateStarting, StateRunning, StateStopping, StateStopped},
+		"fail":  {StateStopped, StateStarting, StateBackoff, StateStarting, StateFatal},
+	}
+
+	waitFor(t, "all events dispatched", func() bool {
+		lock.Lock()
+		defer lock.Unlock()
+
+		return len(transitions["sleep"]) == len(expects["sleep"]) && len(transitions["fail"]) == len(expects["fail"]) && len(published) == 8
+	})
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	for name, expect := range expects {
+		for i, state := range expect {
+			if transitions[name][i] != state {
+				t.Errorf("%s: expect transitions %v, got %v", name, expect, transitions[name])
+				break
+			}
+		}
+	}
+}
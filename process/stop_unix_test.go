@@ -0,0 +1,95 @@
+//go:build !windows
+// +build !windows
+
+package process
+
+import (
+	"context"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// processAlive 判断进程是否存在，僵尸进程视为已经退出
+func processAlive(pid int) bool {
+	data, err := ioutil.ReadFile("/proc/" + strconv.Itoa(pid) + "/stat")
+	if err != nil {
+		return syscall.Kill(pid, 0) == nil
+	}
+
+	fields := strings.Fields(string(data[strings.LastIndex(string(data), ")")+1:]))
+	return len(fields) > 0 && fields[0] != "Z"
+}
+
+func TestStopEscalation(t *testing.T) {
+	// 忽略 SIGTERM 的进程，以及它创建的子进程
+	program := NewProgram("stubborn", `/bin/sh -c 'trap "" TERM; sleep 30 & echo $!; wait'`, "", 1)
+	program.StopTimeout = 200 * time.Millisecond
+
+	graceful := NewProgram("graceful", `/bin/sh -c 'trap "exit 7" INT; echo ready; while true; do sleep 0.05; done'`, "", 1)
+	graceful.StopSignal = syscall.SIGINT
+
+	manager := NewManager(time.Second, nil)
+	if err := manager.AddPrograms(program, graceful); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		manager.Watch(ctx)
+		close(done)
+	}()
+
+	proc := program.Processes()[0]
+	waitFor(t, "child started", func() bool { return len(proc.Tail(1)) == 1 })
+
+	childPID, _ := strconv.Atoi(proc.Tail(1)[0].Line)
+	pid := proc.GetPID()
+
+	start := time.Now()
+	stopped := make(chan error, 1)
+	go func() {
+		stopped <- manager.StopProgram("stubborn")
+	}()
+
+	// 等待进程退出期间不能阻塞状态查询
+	waitFor(t, "process stopping", func() bool { return proc.GetState() == StateStopping })
+	queryStart := time.Now()
+	if status := manager.Status(); len(status) != 2 {
+		t.Errorf("unexpected status %+v", status)
+	}
+	if elapsed := time.Since(queryStart); elapsed > 50*time.Millisecond {
+		t.Errorf("expect status returned immediately while stopping, took %s", elapsed)
+	}
+
+	if err := <-stopped; err != nil {
+		t.Fatal(err)
+	}
+
+	if elapsed := time.Since(start); elapsed < 200*time.Millisecond || elapsed > 3*time.Second {
+		t.Errorf("expect stop to wait for stop timeout, took %s", elapsed)
+	}
+
+	if processAlive(pid) || processAlive(childPID) {
+		t.Errorf("expect process group killed, process alive: %v, child alive: %v", processAlive(pid), processAlive(childPID))
+	}
+
+	gracefulProc := graceful.Processes()[0]
+	waitFor(t, "graceful process started", func() bool { return len(gracefulProc.Tail(1)) == 1 })
+	gracefulPID := gracefulProc.GetPID()
+
+	cancel()
+	<-done
+
+	if processAlive(gracefulPID) {
+		t.Errorf("expect all processes exited when Watch returned")
+	}
+
+	if code := gracefulProc.GetExitCode(); code != 7 {
+		t.Errorf("expect process stopped by SIGINT with exit code 7, got %d", code)
+	}
+}
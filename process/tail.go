@@ -0,0 +1,118 @@
+package process
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultTailLines 每个进程在内存中保留的最近输出行数
+const DefaultTailLines = 200
+
+// OutputLine 进程输出的一行内容
+type OutputLine struct {
+	Time    time.Time  `json:"time"`
+	Process string     `json:"process"`
+	Type    OutputType `json:"type"`
+	Line    string     `json:"line"`
+}
+
+// tailBuffer 保存进程最近输出的环形缓冲区
+type tailBuffer struct {
+	lock  sync.Mutex
+	lines []OutputLine
+	next  int
+	full  bool
+}
+
+func newTailBuffer(size int) *tailBuffer {
+	return &tailBuffer{lines: make([]OutputLine, size)}
+}
+
+// write 写入一行输出，缓冲区满时覆盖最早的输出
+func (buffer *tailBuffer) write(line OutputLine) {
+	buffer.lock.Lock()
+	defer buffer.lock.Unlock()
+
+	if len(buffer.lines) == 0 {
+		return
+	}
+
+	buffer.lines[buffer.next] = line
+	buffer.next = (buffer.next + 1) % len(buffer.lines)
+	if buffer.next == 0 {
+		buffer.full = true
+	}
+}
+
+// tail 按照时间顺序返回最近的 n 行输出，n <= 0 时返回全部
+func (buffer *tailBuffer) tail(n int) []OutputLine {
+	buffer.lock.Lock()
+	defer buffer.lock.Unlock()
+
+	count := buffer.next
+	if buffer.full {
+		count = len(buffer.lines)
+	}
+
+	if n <= 0 || n > count {
+		n = count
+	}
+
+	result := make([]OutputLine, n)
+	start := buffer.next - n
+	for i := 0; i < n; i++ {
+		result[i] = buffer.lines[(start+i+len(buffer.lines))%len(buffer.lines)]
+	}
+
+	return result
+}
+
+// Tail 获取进程最近的 n 行输出，n <= 0 时返回缓冲区中的全部输出
+func (process *Process) Tail(n int) []OutputLine {
+	return process.output.tail(n)
+}
+
+// Tail 获取进程最近的 n 行输出，name 为进程名称（program/0）时返回该进程的输出，
+// 为程序名称时返回程序所有进程的输出，按照时间排序
+func (manager *Manager) Tail(name string, n int) ([]OutputLine, error) {
+	manager.lock.RLock()
+	defer manager.lock.RUnlock()
+
+	programName := name
+	if pos := strings.LastIndex(name, "/"); pos > 0 {
+		programName = name[:pos]
+	}
+
+	program, err := manager.getProgram(programName)
+	if err != nil {
+		return nil, err
+	}
+
+	lines := make([]OutputLine, 0)
+	found := false
+	for _, process := range program.processes {
+		if programName != name && process.name != name {
+			continue
+		}
+
+		found = true
+		lines = append(lines, process.Tail(n)...)
+	}
+
+	if !found {
+		return nil, fmt.Errorf("%w: %s", ErrProcessNotFound, name)
+	}
+
+	sort.SliceStable(lines, func(i, j int) bool {
+		return lines[i].Time.Before(lines[j].Time)
+	})
+
+	if n > 0 && len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+
+	return lines, nil
+}
@@ -0,0 +1,26 @@
+package process
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestTailBuffer(t *testing.T) {
+	buffer := newTailBuffer(3)
+	if lines := buffer.tail(10); len(lines) != 0 {
+		t.Errorf("expect empty buffer, got %v", lines)
+	}
+
+	for i := 0; i < 5; i++ {
+		buffer.write(OutputLine{Line: strconv.Itoa(i)})
+	}
+
+	lines := buffer.tail(0)
+	if len(lines) != 3 || lines[0].Line != "2" || lines[2].Line != "4" {
+		t.Errorf("expect last 3 lines, got %v", lines)
+	}
+
+	if lines := buffer.tail(2); len(lines) != 2 || lines[0].Line != "3" || lines[1].Line != "4" {
+		t.Errorf("expect last 2 lines, got %v", lines)
+	}
+}